@@ -0,0 +1,198 @@
+package rirs
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aredoff/rirs/parser"
+)
+
+var (
+	ErrAbuseNotFound = errors.New("abuse contact not found")
+)
+
+// Abuse is the abuse contact found for an address or an ASN
+type Abuse struct {
+	Email  string
+	Source string
+	// Chain lists the objects that led to Email, from the queried inetnum, inet6num
+	// or aut-num through its parents and organisation to the role or irt holding the mailbox
+	Chain []interface{}
+}
+
+// AbuseFinder finds abuse mailboxes the way the RIPE abuse-finder does, against local data.
+//
+// Starting from the most specific inetnum or inet6num of an address it looks at
+// the abuse-c of the object, then at the abuse-c of its organisation, then at its
+// mnt-irt irt objects, and moves on to the parent when none holds an abuse-mailbox.
+type AbuseFinder struct {
+	ips      *IPIndex
+	resolver *Resolver
+
+	mu      sync.RWMutex
+	sources []string
+	// aut-num objects by source and upper case AS number
+	asns map[string]map[string]*parser.ASN
+}
+
+func NewAbuseFinder(ips *IPIndex, resolver *Resolver) *AbuseFinder {
+	return &AbuseFinder{
+		ips:      ips,
+		resolver: resolver,
+		asns:     make(map[string]map[string]*parser.ASN),
+	}
+}
+
+// AbuseFinder builds an AbuseFinder from the given sources, or from all sources.
+// Malformed entries are skipped.
+func (d *Database) AbuseFinder(sources ...string) (*AbuseFinder, error) {
+	sources, err := d.resolveSources(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := d.IPIndex(sources...)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := d.Resolver(sources...)
+	if err != nil {
+		return nil, err
+	}
+
+	finder := NewAbuseFinder(ips, resolver)
+	for _, source := range sources {
+		for asn, err := range d.ASNs(source) {
+			if errors.Is(err, ErrMalformed) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			finder.AddASN(source, asn)
+		}
+	}
+	return finder, nil
+}
+
+// AddASN makes an aut-num available to AbuseContactASN. Sources are searched in
+// the order they were first added.
+func (f *AbuseFinder) AddASN(source string, asn *parser.ASN) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	asns, ok := f.asns[source]
+	if !ok {
+		asns = make(map[string]*parser.ASN)
+		f.asns[source] = asns
+		f.sources = append(f.sources, source)
+	}
+	asns[strings.ToUpper(asn.ASNumber)] = asn
+}
+
+// AbuseContact returns the abuse contact responsible for addr. It returns an
+// error wrapping ErrAbuseNotFound when no allocation up the chain has one.
+func (f *AbuseFinder) AbuseContact(addr netip.Addr) (*Abuse, error) {
+	allocations := f.ips.LookupIP(addr)
+	if len(allocations) == 0 {
+		return nil, fmt.Errorf("%w: no allocation contains %s", ErrAbuseNotFound, addr)
+	}
+
+	chain := make([]interface{}, 0, len(allocations)+2)
+	for _, allocation := range allocations {
+		var abuseC, org string
+		var mntIrt []string
+		if inetnum := allocation.InetNum; inetnum != nil {
+			chain = append(chain, inetnum)
+			abuseC, org, mntIrt = inetnum.AbuseC, inetnum.Org, inetnum.MntIrt
+		} else {
+			inet6num := allocation.Inet6Num
+			chain = append(chain, inet6num)
+			abuseC, org, mntIrt = inet6num.AbuseC, inet6num.Org, inet6num.MntIrt
+		}
+
+		if abuse := f.find(allocation.Source, chain, abuseC, org, mntIrt); abuse != nil {
+			return abuse, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAbuseNotFound, addr)
+}
+
+// AbuseContactASN returns the abuse contact of an aut-num, given as "AS3333" or "3333".
+// aut-num objects have no parents, so only the object and its organisation are searched.
+func (f *AbuseFinder) AbuseContactASN(asn string) (*Abuse, error) {
+	key := strings.ToUpper(strings.TrimSpace(asn))
+	if _, err := strconv.ParseUint(key, 10, 32); err == nil {
+		key = "AS" + key
+	}
+	if !isASN(key) {
+		return nil, fmt.Errorf("invalid ASN %q", asn)
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	found := false
+	for _, source := range f.sources {
+		autNum, ok := f.asns[source][key]
+		if !ok {
+			continue
+		}
+		found = true
+		if abuse := f.find(source, []interface{}{autNum}, autNum.AbuseC, autNum.Org, nil); abuse != nil {
+			return abuse, nil
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: no aut-num %s", ErrAbuseNotFound, key)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAbuseNotFound, key)
+}
+
+// find looks for an abuse-mailbox behind the abuse-c, org and mnt-irt references
+// of the last object of chain
+func (f *AbuseFinder) find(source string, chain []interface{}, abuseC, org string, mntIrt []string) *Abuse {
+	if role := f.abuseRole(source, abuseC); role != nil {
+		return newAbuse(source, chain, role, role.AbuseMailbox)
+	}
+
+	if organization := f.resolver.Organization(source, org); organization != nil {
+		if role := f.abuseRole(source, organization.AbuseC); role != nil {
+			return newAbuse(source, append(chain, organization), role, role.AbuseMailbox)
+		}
+	}
+
+	for _, name := range mntIrt {
+		if irt := f.resolver.Irt(source, name); irt != nil && irt.AbuseMailbox != "" {
+			return newAbuse(source, chain, irt, irt.AbuseMailbox)
+		}
+	}
+	return nil
+}
+
+// abuseRole resolves an abuse-c handle to a role that has an abuse-mailbox
+func (f *AbuseFinder) abuseRole(source, handle string) *parser.Role {
+	if handle == "" {
+		return nil
+	}
+	contact := f.resolver.Contact(source, handle)
+	if contact.Role == nil || contact.Role.AbuseMailbox == "" {
+		return nil
+	}
+	return contact.Role
+}
+
+func newAbuse(source string, chain []interface{}, holder interface{}, mailbox string) *Abuse {
+	// Copy, chain is shared with the callers that keep walking up
+	objects := make([]interface{}, 0, len(chain)+1)
+	objects = append(objects, chain...)
+	return &Abuse{
+		Email:  mailbox,
+		Source: source,
+		Chain:  append(objects, holder),
+	}
+}
@@ -0,0 +1,153 @@
+package rirs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aredoff/rirs/fs"
+)
+
+const (
+	// checksumAttempts is how many times a dump is downloaded before a mismatch fails the sync
+	checksumAttempts = 3
+	// maxChecksumSize bounds the checksum files read into memory
+	maxChecksumSize = 1 << 20
+)
+
+var (
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// errNoChecksum is returned when the checksum file of a dump is not published
+	errNoChecksum = errors.New("checksum not published")
+)
+
+// checksumHash returns the hash a checksum file holds, chosen by its extension
+func checksumHash(checksumURL string) (func() hash.Hash, error) {
+	switch strings.ToLower(path.Ext(checksumURL)) {
+	case ".md5":
+		return md5.New, nil
+	case ".sha1":
+		return sha1.New, nil
+	case ".sha256":
+		return sha256.New, nil
+	}
+	return nil, fmt.Errorf("unknown checksum type of %s", checksumURL)
+}
+
+// downloadVerified downloads url like downloadFile and checks it against the checksum
+// published at checksumURL, unless the metadata of an unchanged file says it was
+// verified before. A file that does not match is downloaded again, up to
+// checksumAttempts times. Files without a published checksum are checked again on
+// the next sync.
+func downloadVerified(ctx context.Context, dir *fs.Folder, url, checksumURL string) (*download, error) {
+	newHash, err := checksumHash(checksumURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		download, err := downloadFile(ctx, dir, url)
+		if err != nil {
+			return nil, err
+		}
+		if !download.changed && download.meta.Verified {
+			return download, nil
+		}
+
+		expected, err := fetchChecksum(ctx, checksumURL, path.Base(url), newHash().Size())
+		if errors.Is(err, errNoChecksum) {
+			return download, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		actual, err := fileChecksum(download.path, newHash())
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(expected, actual) {
+			download.meta.Verified = true
+			return download, nil
+		}
+
+		// Drop the file so the next attempt downloads it from scratch
+		os.Remove(download.path)
+		if attempt == checksumAttempts {
+			return nil, fmt.Errorf("%w: %s is %x, %s says %x", ErrChecksumMismatch, url, actual, checksumURL, expected)
+		}
+	}
+}
+
+// fetchChecksum downloads a checksum file and returns the checksum of fileName in it.
+// Both "<hex>  <file>" and "MD5 (<file>) = <hex>" lines are understood; a file
+// holding a single checksum is used whatever name it gives.
+func fetchChecksum(ctx context.Context, checksumURL, fileName string, size int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checksumURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", errNoChecksum, checksumURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status for %s: %s", checksumURL, resp.Status)
+	}
+
+	var found [][]byte
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxChecksumSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, field := range strings.FieldsFunc(line, isChecksumSeparator) {
+			sum, err := hex.DecodeString(field)
+			if err != nil || len(sum) != size {
+				continue
+			}
+			if strings.Contains(line, fileName) {
+				return sum, nil
+			}
+			found = append(found, sum)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", checksumURL, err)
+	}
+	if len(found) != 1 {
+		return nil, fmt.Errorf("no checksum for %s in %s", fileName, checksumURL)
+	}
+	return found[0], nil
+}
+
+func isChecksumSeparator(r rune) bool {
+	return r == ' ' || r == '\t' || r == '=' || r == '(' || r == ')' || r == '*'
+}
+
+func fileChecksum(filePath string, h hash.Hash) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	return h.Sum(nil), nil
+}
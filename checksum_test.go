@@ -0,0 +1,65 @@
+package rirs
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchChecksum(t *testing.T) {
+	first, second := md5.Sum([]byte("first")), md5.Sum([]byte("second"))
+	firstHex, secondHex := hex.EncodeToString(first[:]), hex.EncodeToString(second[:])
+	files := map[string]string{
+		"/gnu.md5":       firstHex + "  first.db.gz\n" + secondHex + " *second.db.gz\n",
+		"/bsd.md5":       "MD5 (first.db.gz) = " + firstHex + "\nMD5 (second.db.gz) = " + secondHex + "\n",
+		"/single.md5":    "MD5 (renamed.db.gz) = " + secondHex + "\n",
+		"/ambiguous.md5": firstHex + "  other.db.gz\n" + secondHex + "  another.db.gz\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		fileName string
+		want     []byte
+		wantErr  error
+	}{
+		{name: "gnu", path: "/gnu.md5", fileName: "second.db.gz", want: second[:]},
+		{name: "bsd", path: "/bsd.md5", fileName: "second.db.gz", want: second[:]},
+		{name: "single checksum", path: "/single.md5", fileName: "second.db.gz", want: second[:]},
+		{name: "no matching name", path: "/ambiguous.md5", fileName: "second.db.gz"},
+		{name: "not published", path: "/missing.md5", fileName: "second.db.gz", wantErr: errNoChecksum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchChecksum(t.Context(), server.URL+tt.path, tt.fileName, md5.Size)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("fetchChecksum() = %x, want an error", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("fetchChecksum() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("fetchChecksum() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package rirs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aredoff/rirs/parser"
+)
+
+// contactClasses are the classes a Resolver keeps in memory
+var contactClasses = []string{"person", "role", "organisation", "irt"}
+
+// Contact is a nic-handle resolved to the person or role object it names.
+// Both are nil when the handle is not found in the source.
+type Contact struct {
+	Handle string
+	Person *parser.Person
+	Role   *parser.Role
+}
+
+// Name returns the name of the person or role
+func (c *Contact) Name() string {
+	switch {
+	case c.Person != nil:
+		return c.Person.Name
+	case c.Role != nil:
+		return c.Role.Name
+	}
+	return ""
+}
+
+// Email returns the e-mail addresses of the person or role
+func (c *Contact) Email() []string {
+	switch {
+	case c.Person != nil:
+		return c.Person.Email
+	case c.Role != nil:
+		return c.Role.Email
+	}
+	return nil
+}
+
+// Resolved reports whether the handle was found
+func (c *Contact) Resolved() bool {
+	return c.Person != nil || c.Role != nil
+}
+
+// Contacts holds the resolved contacts of one object
+type Contacts struct {
+	AdminC []*Contact
+	TechC  []*Contact
+	AbuseC *Contact
+}
+
+// ResolvedOrganization is an organisation with its contacts resolved
+type ResolvedOrganization struct {
+	Organization *parser.Organization
+	Contacts     Contacts
+}
+
+// ResolvedInetNum is an inetnum with its contacts and organisation resolved
+type ResolvedInetNum struct {
+	Source       string
+	InetNum      *parser.InetNum
+	Contacts     Contacts
+	Organization *ResolvedOrganization
+}
+
+// ResolvedInet6Num is an inet6num with its contacts and organisation resolved
+type ResolvedInet6Num struct {
+	Source       string
+	Inet6Num     *parser.Inet6Num
+	Contacts     Contacts
+	Organization *ResolvedOrganization
+}
+
+// ResolvedASN is an aut-num with its contacts and organisation resolved
+type ResolvedASN struct {
+	Source       string
+	ASN          *parser.ASN
+	Contacts     Contacts
+	Organization *ResolvedOrganization
+}
+
+// Resolver follows the nic-handles and org references of objects to the person,
+// role and organisation objects of the same source. References never cross sources.
+type Resolver struct {
+	mu      sync.RWMutex
+	sources map[string]*contactSource
+}
+
+// contactSource holds the objects of one source keyed by their normalized handle
+type contactSource struct {
+	persons       map[string]*parser.Person
+	roles         map[string]*parser.Role
+	organizations map[string]*parser.Organization
+	irts          map[string]*parser.Irt
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{sources: make(map[string]*contactSource)}
+}
+
+// Resolver loads the person, role, organisation and irt objects of the given
+// sources, or of all sources, into a Resolver. Malformed entries are skipped.
+func (d *Database) Resolver(sources ...string) (*Resolver, error) {
+	sources, err := d.resolveSources(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := NewResolver()
+	for _, source := range sources {
+		if !d.folder.Exist(source) {
+			return nil, fmt.Errorf("source %s not found", source)
+		}
+		folder, err := d.folder.SubFolder(source)
+		if err != nil {
+			return nil, err
+		}
+		db := parser.NewRipeDatabase()
+		for stored, err := range readFolder(folder, contactClasses...) {
+			if errors.Is(err, ErrMalformed) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := stored.t.save(db, stored.obj); err != nil {
+				return nil, err
+			}
+		}
+		resolver.AddSource(source, db)
+	}
+	return resolver, nil
+}
+
+// AddSource makes the objects of db available to lookups in source, replacing
+// what was added for it before. Only persons, roles, organisations and irts are used.
+func (r *Resolver) AddSource(source string, db *parser.RipeDatabase) {
+	contacts := &contactSource{
+		persons:       normalizeKeys(db.Persons),
+		roles:         normalizeKeys(db.Roles),
+		organizations: normalizeKeys(db.Organizations),
+		irts:          normalizeKeys(db.Irts),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sources[source] = contacts
+}
+
+// Contact resolves a nic-handle of source. Handles are matched case-insensitively,
+// as registries treat them.
+func (r *Resolver) Contact(source, handle string) *Contact {
+	contact := &Contact{Handle: handle}
+	db := r.source(source)
+	if db == nil || handle == "" {
+		return contact
+	}
+
+	key := normalizeHandle(handle)
+	if person, ok := db.persons[key]; ok {
+		contact.Person = person
+		return contact
+	}
+	if role, ok := db.roles[key]; ok {
+		contact.Role = role
+	}
+	return contact
+}
+
+// Organization returns the organisation orgID of source, or nil when it is unknown
+func (r *Resolver) Organization(source, orgID string) *parser.Organization {
+	db := r.source(source)
+	if db == nil || orgID == "" {
+		return nil
+	}
+	return db.organizations[normalizeHandle(orgID)]
+}
+
+// Irt returns the irt object of source, or nil when it is unknown
+func (r *Resolver) Irt(source, irt string) *parser.Irt {
+	db := r.source(source)
+	if db == nil || irt == "" {
+		return nil
+	}
+	return db.irts[normalizeHandle(irt)]
+}
+
+func (r *Resolver) ResolveInetNum(source string, inetnum *parser.InetNum) *ResolvedInetNum {
+	return &ResolvedInetNum{
+		Source:       source,
+		InetNum:      inetnum,
+		Contacts:     r.contacts(source, &inetnum.BaseObject, inetnum.AbuseC),
+		Organization: r.resolveOrganization(source, inetnum.Org),
+	}
+}
+
+func (r *Resolver) ResolveInet6Num(source string, inet6num *parser.Inet6Num) *ResolvedInet6Num {
+	return &ResolvedInet6Num{
+		Source:       source,
+		Inet6Num:     inet6num,
+		Contacts:     r.contacts(source, &inet6num.BaseObject, inet6num.AbuseC),
+		Organization: r.resolveOrganization(source, inet6num.Org),
+	}
+}
+
+func (r *Resolver) ResolveASN(source string, asn *parser.ASN) *ResolvedASN {
+	return &ResolvedASN{
+		Source:       source,
+		ASN:          asn,
+		Contacts:     r.contacts(source, &asn.BaseObject, asn.AbuseC),
+		Organization: r.resolveOrganization(source, asn.Org),
+	}
+}
+
+func (r *Resolver) resolveOrganization(source, orgID string) *ResolvedOrganization {
+	org := r.Organization(source, orgID)
+	if org == nil {
+		return nil
+	}
+	return &ResolvedOrganization{
+		Organization: org,
+		Contacts:     r.contacts(source, &org.BaseObject, org.AbuseC),
+	}
+}
+
+func (r *Resolver) contacts(source string, base *parser.BaseObject, abuseC string) Contacts {
+	contacts := Contacts{
+		AdminC: make([]*Contact, 0, len(base.AdminC)),
+		TechC:  make([]*Contact, 0, len(base.TechC)),
+	}
+	for _, handle := range base.AdminC {
+		contacts.AdminC = append(contacts.AdminC, r.Contact(source, handle))
+	}
+	for _, handle := range base.TechC {
+		contacts.TechC = append(contacts.TechC, r.Contact(source, handle))
+	}
+	if abuseC != "" {
+		contacts.AbuseC = r.Contact(source, abuseC)
+	}
+	return contacts
+}
+
+func (r *Resolver) source(source string) *contactSource {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sources[source]
+}
+
+// normalizeHandle returns the key a handle is stored and looked up under
+func normalizeHandle(handle string) string {
+	return strings.ToUpper(strings.TrimSpace(handle))
+}
+
+// normalizeKeys re-keys objects by their normalized handle. When two handles only
+// differ in case, the one that sorts last wins.
+func normalizeKeys[T any](objects map[string]*T) map[string]*T {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	normalized := make(map[string]*T, len(objects))
+	for _, key := range keys {
+		normalized[normalizeHandle(key)] = objects[key]
+	}
+	return normalized
+}
@@ -0,0 +1,186 @@
+package rirs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"slices"
+
+	"github.com/aredoff/rirs/fs"
+	"github.com/aredoff/rirs/parser"
+)
+
+var (
+	ErrMalformed = errors.New("malformed object")
+)
+
+// storedObject is an object read back from a database folder
+type storedObject struct {
+	t   objectType
+	obj interface{}
+}
+
+// LoadDatabase rebuilds the in-memory database of one source from the folder
+// Sync wrote it to, in either the JSON or the JSON Lines format.
+// Objects sharing a primary key collapse into the last one read, and malformed
+// entries are skipped like in the other readers.
+func LoadDatabase(folder *fs.Folder) (*parser.RipeDatabase, error) {
+	db := parser.NewRipeDatabase()
+	if err := replay(folder, db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// replay decodes every object stored in folder and saves it into storage,
+// skipping malformed entries
+func replay(folder *fs.Folder, storage parser.Storage) error {
+	for stored, err := range readFolder(folder) {
+		if errors.Is(err, ErrMalformed) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := stored.t.save(storage, stored.obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFolder streams the objects of a source folder in one pass, limited to the
+// given classes unless none are given
+func readFolder(folder *fs.Folder, classes ...string) iter.Seq2[storedObject, error] {
+	wanted := func(class string) bool {
+		return len(classes) == 0 || slices.Contains(classes, class)
+	}
+
+	return func(yield func(storedObject, error) bool) {
+		if folder.Exist(jsonlFileName) {
+			for stored, err := range readJSONL(folder.GetPath(jsonlFileName), wanted) {
+				if !yield(stored, err) {
+					return
+				}
+			}
+			return
+		}
+
+		for _, objType := range objectTypes {
+			if !wanted(objType.class) {
+				continue
+			}
+			filename := objType.file + ".json"
+			if !folder.Exist(filename) {
+				continue
+			}
+			for stored, err := range readJSON(folder.GetPath(filename), objType) {
+				if !yield(stored, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// readJSON streams the objects of one JSON file written by storage. Objects that
+// do not decode are reported with ErrMalformed and skipped; a syntax error ends the file.
+func readJSON(path string, t objectType) iter.Seq2[storedObject, error] {
+	return func(yield func(storedObject, error) bool) {
+		file, err := os.Open(path)
+		if err != nil {
+			yield(storedObject{}, fmt.Errorf("failed to open file %s: %w", path, err))
+			return
+		}
+		defer file.Close()
+
+		decoder := json.NewDecoder(bufio.NewReaderSize(file, bufferSize))
+		if _, err := decoder.Token(); err != nil {
+			yield(storedObject{}, fmt.Errorf("failed to read %s: %w", path, err))
+			return
+		}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				yield(storedObject{}, fmt.Errorf("failed to read key in %s: %w", path, err))
+				return
+			}
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				yield(storedObject{}, fmt.Errorf("failed to read %v in %s: %w", key, path, err))
+				return
+			}
+
+			obj := t.new()
+			if err := json.Unmarshal(raw, obj); err != nil {
+				if !yield(storedObject{}, fmt.Errorf("%w: %v in %s: %v", ErrMalformed, key, path, err)) {
+					return
+				}
+				continue
+			}
+			if !yield(storedObject{t: t, obj: obj}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// readJSONL streams the objects of a JSON Lines file written by jsonlStorage whose
+// class is wanted. Lines that do not decode are reported with ErrMalformed and skipped.
+func readJSONL(path string, wanted func(class string) bool) iter.Seq2[storedObject, error] {
+	return func(yield func(storedObject, error) bool) {
+		file, err := os.Open(path)
+		if err != nil {
+			yield(storedObject{}, fmt.Errorf("failed to open file %s: %w", path, err))
+			return
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, bufferSize), bufferSize)
+		lineNumber := 0
+		for scanner.Scan() {
+			lineNumber++
+			stored, err := decodeJSONLRecord(scanner.Bytes(), wanted)
+			if err != nil {
+				if !yield(storedObject{}, fmt.Errorf("%w: line %d of %s: %v", ErrMalformed, lineNumber, path, err)) {
+					return
+				}
+				continue
+			}
+			if stored.obj == nil {
+				continue
+			}
+			if !yield(stored, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(storedObject{}, fmt.Errorf("failed to read %s: %w", path, err))
+		}
+	}
+}
+
+// decodeJSONLRecord decodes one line. Records of unknown or unwanted types decode
+// to an empty storedObject without decoding their object.
+func decodeJSONLRecord(line []byte, wanted func(class string) bool) (storedObject, error) {
+	var record struct {
+		Type   string          `json:"type"`
+		Object json.RawMessage `json:"object"`
+	}
+	if err := json.Unmarshal(line, &record); err != nil {
+		return storedObject{}, err
+	}
+	t, ok := objectTypeByClass(record.Type)
+	if !ok || !wanted(t.class) {
+		return storedObject{}, nil
+	}
+	obj := t.new()
+	if err := json.Unmarshal(record.Object, obj); err != nil {
+		return storedObject{}, err
+	}
+	return storedObject{t: t, obj: obj}, nil
+}
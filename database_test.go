@@ -0,0 +1,72 @@
+package rirs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aredoff/rirs/fs"
+	"github.com/aredoff/rirs/parser"
+)
+
+func TestLoadDatabaseSkipsMalformed(t *testing.T) {
+	folder, err := fs.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := NewJSONLStorage(folder, "ripe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.SaveASN(&parser.ASN{BaseObject: parser.BaseObject{Key: "AS1"}, ASNumber: "AS1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A truncated line and an object of the wrong shape between two good ones
+	file, err := os.OpenFile(folder.GetPath(jsonlFileName), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"type": "aut-num", "object": {"aut-num"` + "\n")
+	file.WriteString(`{"type": "aut-num", "object": {"ASNumber": 3333}}` + "\n")
+	file.WriteString(`{"type": "aut-num", "source": "ripe", "object": {"Key": "AS2", "ASNumber": "AS2"}}` + "\n")
+	file.Close()
+
+	db, err := LoadDatabase(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.ASNs) != 2 || db.ASNs["AS1"] == nil || db.ASNs["AS2"] == nil {
+		t.Errorf("ASNs = %v, want AS1 and AS2", db.ASNs)
+	}
+}
+
+func TestReadFolderClasses(t *testing.T) {
+	folder, err := fs.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := NewJSONLStorage(folder, "ripe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.SaveASN(&parser.ASN{BaseObject: parser.BaseObject{Key: "AS1"}})
+	storage.SavePerson(&parser.Person{BaseObject: parser.BaseObject{Key: "P1-RIPE"}})
+	storage.SaveRole(&parser.Role{BaseObject: parser.BaseObject{Key: "R1-RIPE"}})
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	classes := make([]string, 0)
+	for stored, err := range readFolder(folder, "person", "role") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes = append(classes, stored.t.class)
+	}
+	if len(classes) != 2 || classes[0] != "person" || classes[1] != "role" {
+		t.Errorf("classes = %v, want [person role]", classes)
+	}
+}
@@ -0,0 +1,238 @@
+package rirs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aredoff/rirs/parser"
+)
+
+// Reference is an object found by an inverse query
+type Reference struct {
+	Source string
+	// Class is the RPSL class of Object, such as "inetnum" or "aut-num"
+	Class  string
+	Key    string
+	Object interface{}
+}
+
+// InverseIndex answers the inverse queries of whois -i: the objects maintained by
+// a mntner, the objects referencing an organisation and the objects naming a
+// nic-handle as admin-c or tech-c. Handles are matched case-insensitively.
+type InverseIndex struct {
+	mu     sync.RWMutex
+	mntBy  map[string][]*Reference
+	org    map[string][]*Reference
+	adminC map[string][]*Reference
+	techC  map[string][]*Reference
+}
+
+func NewInverseIndex() *InverseIndex {
+	return &InverseIndex{
+		mntBy:  make(map[string][]*Reference),
+		org:    make(map[string][]*Reference),
+		adminC: make(map[string][]*Reference),
+		techC:  make(map[string][]*Reference),
+	}
+}
+
+// InverseIndex builds an InverseIndex from every object of the given sources, or
+// of all sources. Malformed entries are skipped.
+func (d *Database) InverseIndex(sources ...string) (*InverseIndex, error) {
+	sources, err := d.resolveSources(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := NewInverseIndex()
+	for _, source := range sources {
+		if !d.folder.Exist(source) {
+			return nil, fmt.Errorf("source %s not found", source)
+		}
+		folder, err := d.folder.SubFolder(source)
+		if err != nil {
+			return nil, err
+		}
+		storage := idx.Storage(source)
+		for stored, err := range readFolder(folder) {
+			if errors.Is(err, ErrMalformed) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := stored.t.save(storage, stored.obj); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return idx, nil
+}
+
+// Storage returns a parser.Storage that adds the objects saved to it under source,
+// so the index can also be filled while parsing
+func (idx *InverseIndex) Storage(source string) parser.Storage {
+	return &inverseStorage{idx: idx, source: source}
+}
+
+// MntBy returns the objects maintained by mntner
+func (idx *InverseIndex) MntBy(mntner string) []*Reference {
+	return idx.lookup(idx.mntBy, mntner)
+}
+
+// Org returns the objects whose org attribute is orgID
+func (idx *InverseIndex) Org(orgID string) []*Reference {
+	return idx.lookup(idx.org, orgID)
+}
+
+// AdminC returns the objects with nicHdl as admin-c
+func (idx *InverseIndex) AdminC(nicHdl string) []*Reference {
+	return idx.lookup(idx.adminC, nicHdl)
+}
+
+// TechC returns the objects with nicHdl as tech-c
+func (idx *InverseIndex) TechC(nicHdl string) []*Reference {
+	return idx.lookup(idx.techC, nicHdl)
+}
+
+// Contact returns the objects with nicHdl as admin-c or tech-c, each object once
+func (idx *InverseIndex) Contact(nicHdl string) []*Reference {
+	references := idx.AdminC(nicHdl)
+	seen := make(map[*Reference]struct{}, len(references))
+	for _, reference := range references {
+		seen[reference] = struct{}{}
+	}
+	for _, reference := range idx.TechC(nicHdl) {
+		if _, ok := seen[reference]; !ok {
+			references = append(references, reference)
+		}
+	}
+	return references
+}
+
+func (idx *InverseIndex) lookup(index map[string][]*Reference, handle string) []*Reference {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// Copy so callers can append without racing with writers
+	return append([]*Reference{}, index[strings.ToUpper(handle)]...)
+}
+
+func (idx *InverseIndex) add(source, class string, base *parser.BaseObject, obj interface{}) {
+	reference := &Reference{Source: source, Class: class, Key: base.Key, Object: obj}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	addReference(idx.mntBy, base.MntBy, reference)
+	addReference(idx.adminC, base.AdminC, reference)
+	addReference(idx.techC, base.TechC, reference)
+	if base.Org != "" {
+		addReference(idx.org, []string{base.Org}, reference)
+	}
+}
+
+// addReference adds reference under every handle, once per handle
+func addReference(index map[string][]*Reference, handles []string, reference *Reference) {
+	for i, handle := range handles {
+		handle = strings.ToUpper(handle)
+		duplicate := false
+		for _, previous := range handles[:i] {
+			if strings.EqualFold(previous, handle) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			index[handle] = append(index[handle], reference)
+		}
+	}
+}
+
+// inverseStorage adds the objects of one source to an InverseIndex
+type inverseStorage struct {
+	idx    *InverseIndex
+	source string
+}
+
+func (s *inverseStorage) SaveASN(asn *parser.ASN) error {
+	s.idx.add(s.source, "aut-num", &asn.BaseObject, asn)
+	return nil
+}
+
+func (s *inverseStorage) SaveInetNum(inetnum *parser.InetNum) error {
+	s.idx.add(s.source, "inetnum", &inetnum.BaseObject, inetnum)
+	return nil
+}
+
+func (s *inverseStorage) SaveInet6Num(inet6num *parser.Inet6Num) error {
+	s.idx.add(s.source, "inet6num", &inet6num.BaseObject, inet6num)
+	return nil
+}
+
+func (s *inverseStorage) SaveRoute(route *parser.Route) error {
+	s.idx.add(s.source, "route", &route.BaseObject, route)
+	return nil
+}
+
+func (s *inverseStorage) SaveRoute6(route6 *parser.Route6) error {
+	s.idx.add(s.source, "route6", &route6.BaseObject, route6)
+	return nil
+}
+
+func (s *inverseStorage) SavePerson(person *parser.Person) error {
+	s.idx.add(s.source, "person", &person.BaseObject, person)
+	return nil
+}
+
+func (s *inverseStorage) SaveRole(role *parser.Role) error {
+	s.idx.add(s.source, "role", &role.BaseObject, role)
+	return nil
+}
+
+func (s *inverseStorage) SaveMntner(mntner *parser.Mntner) error {
+	s.idx.add(s.source, "mntner", &mntner.BaseObject, mntner)
+	return nil
+}
+
+func (s *inverseStorage) SaveIrt(irt *parser.Irt) error {
+	s.idx.add(s.source, "irt", &irt.BaseObject, irt)
+	return nil
+}
+
+func (s *inverseStorage) SaveOrganization(org *parser.Organization) error {
+	s.idx.add(s.source, "organisation", &org.BaseObject, org)
+	return nil
+}
+
+func (s *inverseStorage) SaveDomain(domain *parser.Domain) error {
+	s.idx.add(s.source, "domain", &domain.BaseObject, domain)
+	return nil
+}
+
+func (s *inverseStorage) SaveAsSet(asSet *parser.AsSet) error {
+	s.idx.add(s.source, "as-set", &asSet.BaseObject, asSet)
+	return nil
+}
+
+func (s *inverseStorage) SaveRouteSet(routeSet *parser.RouteSet) error {
+	s.idx.add(s.source, "route-set", &routeSet.BaseObject, routeSet)
+	return nil
+}
+
+func (s *inverseStorage) SaveFilterSet(filterSet *parser.FilterSet) error {
+	s.idx.add(s.source, "filter-set", &filterSet.BaseObject, filterSet)
+	return nil
+}
+
+func (s *inverseStorage) SavePeeringSet(peeringSet *parser.PeeringSet) error {
+	s.idx.add(s.source, "peering-set", &peeringSet.BaseObject, peeringSet)
+	return nil
+}
+
+func (s *inverseStorage) SaveRtrSet(rtrSet *parser.RtrSet) error {
+	s.idx.add(s.source, "rtr-set", &rtrSet.BaseObject, rtrSet)
+	return nil
+}
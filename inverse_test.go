@@ -0,0 +1,48 @@
+package rirs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aredoff/rirs/parser"
+)
+
+func TestInverseIndexOrg(t *testing.T) {
+	dump := "role:           RIPE NCC Operations\n" +
+		"nic-hdl:        OPS4-RIPE\n" +
+		"org:            ORG-RIEN1-RIPE\n" +
+		"mnt-by:         RIPE-NCC-MNT\n" +
+		"source:         RIPE\n" +
+		"\n" +
+		"mntner:         RIPE-NCC-MNT\n" +
+		"org:            ORG-RIEN1-RIPE\n" +
+		"source:         RIPE\n" +
+		"\n" +
+		"aut-num:        AS3333\n" +
+		"org:            ORG-RIEN1-RIPE\n" +
+		"mnt-by:         RIPE-NCC-MNT\n" +
+		"source:         RIPE\n"
+	path := filepath.Join(t.TempDir(), "ripe.db")
+	if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := NewInverseIndex()
+	if err := parser.NewParser(idx.Storage("ripe")).ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	classes := make(map[string]bool)
+	for _, ref := range idx.Org("org-rien1-ripe") {
+		classes[ref.Class] = true
+	}
+	for _, class := range []string{"role", "mntner", "aut-num"} {
+		if !classes[class] {
+			t.Errorf("Org() misses the %s", class)
+		}
+	}
+	if n := len(idx.MntBy("RIPE-NCC-MNT")); n != 2 {
+		t.Errorf("MntBy() returned %d references, want 2", n)
+	}
+}
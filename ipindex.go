@@ -0,0 +1,243 @@
+package rirs
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aredoff/rirs/parser"
+)
+
+// Allocation is an inetnum or inet6num object found by an IP lookup
+type Allocation struct {
+	Source string
+	// InetNum is set for IPv4 allocations and Inet6Num for IPv6 ones
+	InetNum  *parser.InetNum
+	Inet6Num *parser.Inet6Num
+	first    netip.Addr
+	last     netip.Addr
+}
+
+// NetName returns the netname of the underlying object
+func (a *Allocation) NetName() string {
+	if a.InetNum != nil {
+		return a.InetNum.NetName
+	}
+	return a.Inet6Num.NetName
+}
+
+// Country returns the country of the underlying object
+func (a *Allocation) Country() string {
+	if a.InetNum != nil {
+		return a.InetNum.Country
+	}
+	return a.Inet6Num.Country
+}
+
+// Org returns the organisation handle of the underlying object
+func (a *Allocation) Org() string {
+	if a.InetNum != nil {
+		return a.InetNum.Org
+	}
+	return a.Inet6Num.Org
+}
+
+// IPIndex answers longest-prefix-match queries over inetnum and inet6num objects.
+// inetnum ranges that are not a single CIDR are split into the CIDRs covering them.
+type IPIndex struct {
+	mu   sync.RWMutex
+	tree prefixTree[*Allocation]
+}
+
+func NewIPIndex() *IPIndex {
+	return &IPIndex{}
+}
+
+// IPIndex builds an IPIndex from the inetnum and inet6num objects of the given
+// sources, or of all sources. Malformed entries are skipped.
+func (d *Database) IPIndex(sources ...string) (*IPIndex, error) {
+	sources, err := d.resolveSources(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := NewIPIndex()
+	for _, source := range sources {
+		for inetnum, err := range d.InetNums(source) {
+			if errors.Is(err, ErrMalformed) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			// Registries carry a few unparsable ranges, they cannot be looked up anyway
+			_ = idx.AddInetNum(source, inetnum)
+		}
+		for inet6num, err := range d.Inet6Nums(source) {
+			if errors.Is(err, ErrMalformed) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			_ = idx.AddInet6Num(source, inet6num)
+		}
+	}
+	return idx, nil
+}
+
+func (idx *IPIndex) AddInetNum(source string, inetnum *parser.InetNum) error {
+	first, last, err := parseRange(inetnum.IPRange)
+	if err != nil {
+		return err
+	}
+	idx.add(&Allocation{Source: source, InetNum: inetnum, first: first, last: last})
+	return nil
+}
+
+func (idx *IPIndex) AddInet6Num(source string, inet6num *parser.Inet6Num) error {
+	first, last, err := parseRange(inet6num.Prefix)
+	if err != nil {
+		return err
+	}
+	idx.add(&Allocation{Source: source, Inet6Num: inet6num, first: first, last: last})
+	return nil
+}
+
+func (idx *IPIndex) add(allocation *Allocation) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, prefix := range rangeToPrefixes(allocation.first, allocation.last) {
+		idx.tree.insert(prefix, allocation)
+	}
+}
+
+// LookupIP returns the allocations containing addr: the most specific one first,
+// followed by its chain of less specific parents. It returns nil when nothing matches.
+func (idx *IPIndex) LookupIP(addr netip.Addr) []*Allocation {
+	addr = addr.Unmap()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	allocations := make([]*Allocation, 0)
+	for _, node := range idx.tree.covering(netip.PrefixFrom(addr, addr.BitLen())) {
+		allocations = append(allocations, node.values...)
+	}
+	if len(allocations) == 0 {
+		return nil
+	}
+
+	// A range split into CIDRs can sit at the same depth as a smaller range,
+	// so order by the size of the whole range rather than by tree depth
+	sort.SliceStable(allocations, func(i, j int) bool {
+		return compareRangeSize(allocations[i], allocations[j]) < 0
+	})
+	return allocations
+}
+
+// parseRange parses an inetnum range such as "193.0.0.0 - 193.0.7.255" or a
+// prefix, including the abbreviated "200.7.84/23" notation used by LACNIC
+func parseRange(value string) (netip.Addr, netip.Addr, error) {
+	if start, end, ok := strings.Cut(value, "-"); ok {
+		first, err := netip.ParseAddr(strings.TrimSpace(start))
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid range %q: %w", value, err)
+		}
+		last, err := netip.ParseAddr(strings.TrimSpace(end))
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid range %q: %w", value, err)
+		}
+		if first.BitLen() != last.BitLen() || last.Less(first) {
+			return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid range %q", value)
+		}
+		return first, last, nil
+	}
+
+	prefix, err := parsePrefix(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid range %q: %w", value, err)
+	}
+	return prefix.Addr(), lastAddr(prefix), nil
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	addr, bits, ok := strings.Cut(value, "/")
+	if ok && !strings.Contains(addr, ":") {
+		// Pad abbreviated IPv4 prefixes, "200.7.84/23" stands for "200.7.84.0/23"
+		for strings.Count(addr, ".") < 3 {
+			addr += ".0"
+		}
+		value = addr + "/" + bits
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix.Masked(), nil
+}
+
+// rangeToPrefixes returns the smallest list of CIDRs covering first to last
+func rangeToPrefixes(first, last netip.Addr) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, 1)
+	for {
+		bits := first.BitLen()
+		// Grow the block while it stays aligned on first and ends before last
+		for bits > 0 {
+			wider := netip.PrefixFrom(first, bits-1).Masked()
+			if wider.Addr() != first || last.Less(lastAddr(wider)) {
+				break
+			}
+			bits--
+		}
+
+		prefix := netip.PrefixFrom(first, bits)
+		prefixes = append(prefixes, prefix)
+
+		end := lastAddr(prefix)
+		if end == last || !end.Next().IsValid() {
+			return prefixes
+		}
+		first = end.Next()
+	}
+}
+
+// lastAddr returns the last address of prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(bytes)*8; i++ {
+		bytes[i/8] |= 0x80 >> uint(i%8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// compareRangeSize orders allocations by the number of addresses they span
+func compareRangeSize(a, b *Allocation) int {
+	aHi, aLo := rangeSize(a.first, a.last)
+	bHi, bLo := rangeSize(b.first, b.last)
+	if aHi != bHi {
+		return cmp.Compare(aHi, bHi)
+	}
+	return cmp.Compare(aLo, bLo)
+}
+
+// rangeSize returns last - first as a 128-bit number split into two halves
+func rangeSize(first, last netip.Addr) (uint64, uint64) {
+	f, l := first.As16(), last.As16()
+	fHi, fLo := binary.BigEndian.Uint64(f[:8]), binary.BigEndian.Uint64(f[8:])
+	lHi, lLo := binary.BigEndian.Uint64(l[:8]), binary.BigEndian.Uint64(l[8:])
+
+	lo := lLo - fLo
+	hi := lHi - fHi
+	if lLo < fLo {
+		hi--
+	}
+	return hi, lo
+}
@@ -0,0 +1,148 @@
+package rirs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aredoff/rirs/fs"
+	"github.com/aredoff/rirs/parser"
+)
+
+const (
+	jsonlFileName = "objects.jsonl"
+)
+
+// jsonlRecord is a single line of the JSON Lines output
+type jsonlRecord struct {
+	Type   string      `json:"type"`
+	Source string      `json:"source"`
+	Object interface{} `json:"object"`
+}
+
+// jsonlStorage writes every object of a source to a single JSON Lines file,
+// one record per line tagged with the object class and the source it came from.
+// It is safe for concurrent use.
+type jsonlStorage struct {
+	mu     sync.Mutex
+	source string
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func NewJSONLStorage(folder *fs.Folder, source string) (*jsonlStorage, error) {
+	filename := folder.GetPath(jsonlFileName)
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", filename, err)
+	}
+
+	return &jsonlStorage{
+		source: source,
+		file:   file,
+		writer: bufio.NewWriterSize(file, bufferSize),
+	}, nil
+}
+
+func (s *jsonlStorage) SaveASN(asn *parser.ASN) error {
+	return s.saveObject("aut-num", asn)
+}
+
+func (s *jsonlStorage) SaveInetNum(inetnum *parser.InetNum) error {
+	return s.saveObject("inetnum", inetnum)
+}
+
+func (s *jsonlStorage) SaveInet6Num(inet6num *parser.Inet6Num) error {
+	return s.saveObject("inet6num", inet6num)
+}
+
+func (s *jsonlStorage) SaveRoute(route *parser.Route) error {
+	return s.saveObject("route", route)
+}
+
+func (s *jsonlStorage) SaveRoute6(route6 *parser.Route6) error {
+	return s.saveObject("route6", route6)
+}
+
+func (s *jsonlStorage) SavePerson(person *parser.Person) error {
+	return s.saveObject("person", person)
+}
+
+func (s *jsonlStorage) SaveRole(role *parser.Role) error {
+	return s.saveObject("role", role)
+}
+
+func (s *jsonlStorage) SaveMntner(mntner *parser.Mntner) error {
+	return s.saveObject("mntner", mntner)
+}
+
+func (s *jsonlStorage) SaveIrt(irt *parser.Irt) error {
+	return s.saveObject("irt", irt)
+}
+
+func (s *jsonlStorage) SaveOrganization(org *parser.Organization) error {
+	return s.saveObject("organisation", org)
+}
+
+func (s *jsonlStorage) SaveDomain(domain *parser.Domain) error {
+	return s.saveObject("domain", domain)
+}
+
+func (s *jsonlStorage) SaveAsSet(asSet *parser.AsSet) error {
+	return s.saveObject("as-set", asSet)
+}
+
+func (s *jsonlStorage) SaveRouteSet(routeSet *parser.RouteSet) error {
+	return s.saveObject("route-set", routeSet)
+}
+
+func (s *jsonlStorage) SaveFilterSet(filterSet *parser.FilterSet) error {
+	return s.saveObject("filter-set", filterSet)
+}
+
+func (s *jsonlStorage) SavePeeringSet(peeringSet *parser.PeeringSet) error {
+	return s.saveObject("peering-set", peeringSet)
+}
+
+func (s *jsonlStorage) SaveRtrSet(rtrSet *parser.RtrSet) error {
+	return s.saveObject("rtr-set", rtrSet)
+}
+
+func (s *jsonlStorage) saveObject(objType string, obj interface{}) error {
+	jsonData, err := json.Marshal(jsonlRecord{
+		Type:   objType,
+		Source: s.source,
+		Object: obj,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal object: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.writer.Write(jsonData); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := s.writer.WriteByte('\n'); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+
+	return nil
+}
+
+func (s *jsonlStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writer.Flush(); err != nil {
+		s.file.Close()
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	return nil
+}
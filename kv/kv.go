@@ -0,0 +1,77 @@
+// Package kv stores parsed RPSL objects in an embedded bbolt database for point lookups.
+// Every source gets a top-level bucket with one nested bucket per object class;
+// objects are keyed by their primary key and encoded as JSON.
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// batchSize is the number of objects written per transaction
+	batchSize = 10000
+)
+
+var (
+	ErrNotFound = errors.New("object not found")
+)
+
+// DB is a bbolt database holding the objects of any number of sources
+type DB struct {
+	db *bolt.DB
+}
+
+// Open opens the database at path, creating it if needed
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	return &DB{db: db}, nil
+}
+
+// stagingBucket is the top-level bucket sources are written into until their
+// storage is closed. It is not a source, Reader.Sources skips it.
+var stagingBucket = []byte(".staging")
+
+// Storage returns a parser.Storage writing the objects of one source.
+// Objects are written into a bucket of their own and only replace the objects
+// stored earlier for the same source when the storage is closed, see Storage.Close
+// and Storage.Abort.
+func (d *DB) Storage(source string) (*Storage, error) {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		staging, err := tx.CreateBucketIfNotExists(stagingBucket)
+		if err != nil {
+			return err
+		}
+		// Left behind by a storage that was never closed or aborted
+		if staging.Bucket([]byte(source)) != nil {
+			if err := staging.DeleteBucket([]byte(source)); err != nil {
+				return err
+			}
+		}
+		_, err = staging.CreateBucket([]byte(source))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bucket for %s: %w", source, err)
+	}
+
+	return &Storage{
+		db:     d.db,
+		source: source,
+	}, nil
+}
+
+// Reader returns a Reader sharing the database handle
+func (d *DB) Reader() *Reader {
+	return &Reader{db: d.db}
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
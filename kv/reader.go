@@ -0,0 +1,182 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aredoff/rirs/parser"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Reader looks objects up by source and primary key without loading whole dumps
+type Reader struct {
+	db *bolt.DB
+}
+
+func (r *Reader) GetASN(source, asn string) (*parser.ASN, error) {
+	obj := &parser.ASN{}
+	if err := r.get(source, "aut-num", asn, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetInetNum(source, ipRange string) (*parser.InetNum, error) {
+	obj := &parser.InetNum{}
+	if err := r.get(source, "inetnum", ipRange, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetInet6Num(source, prefix string) (*parser.Inet6Num, error) {
+	obj := &parser.Inet6Num{}
+	if err := r.get(source, "inet6num", prefix, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetRoute looks a route up by prefix and origin, as in ("193.0.0.0/21", "AS3333")
+func (r *Reader) GetRoute(source, prefix, origin string) (*parser.Route, error) {
+	obj := &parser.Route{}
+	if err := r.get(source, "route", prefix+origin, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetRoute6 looks a route6 up by prefix and origin
+func (r *Reader) GetRoute6(source, prefix, origin string) (*parser.Route6, error) {
+	obj := &parser.Route6{}
+	if err := r.get(source, "route6", prefix+origin, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetPerson(source, nicHdl string) (*parser.Person, error) {
+	obj := &parser.Person{}
+	if err := r.get(source, "person", nicHdl, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetRole(source, nicHdl string) (*parser.Role, error) {
+	obj := &parser.Role{}
+	if err := r.get(source, "role", nicHdl, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetMntner(source, mntner string) (*parser.Mntner, error) {
+	obj := &parser.Mntner{}
+	if err := r.get(source, "mntner", mntner, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetIrt(source, irt string) (*parser.Irt, error) {
+	obj := &parser.Irt{}
+	if err := r.get(source, "irt", irt, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetOrganization(source, orgID string) (*parser.Organization, error) {
+	obj := &parser.Organization{}
+	if err := r.get(source, "organisation", orgID, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetDomain(source, domain string) (*parser.Domain, error) {
+	obj := &parser.Domain{}
+	if err := r.get(source, "domain", domain, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetAsSet(source, name string) (*parser.AsSet, error) {
+	obj := &parser.AsSet{}
+	if err := r.get(source, "as-set", name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetRouteSet(source, name string) (*parser.RouteSet, error) {
+	obj := &parser.RouteSet{}
+	if err := r.get(source, "route-set", name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetFilterSet(source, name string) (*parser.FilterSet, error) {
+	obj := &parser.FilterSet{}
+	if err := r.get(source, "filter-set", name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetPeeringSet(source, name string) (*parser.PeeringSet, error) {
+	obj := &parser.PeeringSet{}
+	if err := r.get(source, "peering-set", name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *Reader) GetRtrSet(source, name string) (*parser.RtrSet, error) {
+	obj := &parser.RtrSet{}
+	if err := r.get(source, "rtr-set", name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Sources returns the names of all stored sources
+func (r *Reader) Sources() ([]string, error) {
+	sources := make([]string, 0)
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if bytes.Equal(name, stagingBucket) {
+				return nil
+			}
+			sources = append(sources, string(name))
+			return nil
+		})
+	})
+	return sources, err
+}
+
+func (r *Reader) get(source, class, key string, obj interface{}) error {
+	return r.db.View(func(tx *bolt.Tx) error {
+		sourceBucket := tx.Bucket([]byte(source))
+		if sourceBucket == nil {
+			return fmt.Errorf("%w: %s %s in %s", ErrNotFound, class, key, source)
+		}
+		bucket := sourceBucket.Bucket([]byte(class))
+		if bucket == nil {
+			return fmt.Errorf("%w: %s %s in %s", ErrNotFound, class, key, source)
+		}
+		value := bucket.Get([]byte(key))
+		if value == nil {
+			return fmt.Errorf("%w: %s %s in %s", ErrNotFound, class, key, source)
+		}
+		// value is only valid inside the transaction, Unmarshal copies what it needs
+		if err := json.Unmarshal(value, obj); err != nil {
+			return fmt.Errorf("failed to decode %s %s: %w", class, key, err)
+		}
+		return nil
+	})
+}
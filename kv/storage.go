@@ -0,0 +1,184 @@
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/aredoff/rirs/parser"
+	bolt "go.etcd.io/bbolt"
+)
+
+type entry struct {
+	class string
+	key   string
+	value []byte
+}
+
+// Storage writes the objects of one source into a DB. Objects are buffered and
+// written in batches; when a key repeats within a class the last object wins.
+type Storage struct {
+	db      *bolt.DB
+	source  string
+	mu      sync.Mutex
+	pending []entry
+}
+
+func (s *Storage) SaveASN(asn *parser.ASN) error {
+	return s.saveObject("aut-num", asn.Key, asn)
+}
+
+func (s *Storage) SaveInetNum(inetnum *parser.InetNum) error {
+	return s.saveObject("inetnum", inetnum.Key, inetnum)
+}
+
+func (s *Storage) SaveInet6Num(inet6num *parser.Inet6Num) error {
+	return s.saveObject("inet6num", inet6num.Key, inet6num)
+}
+
+func (s *Storage) SaveRoute(route *parser.Route) error {
+	return s.saveObject("route", route.Key, route)
+}
+
+func (s *Storage) SaveRoute6(route6 *parser.Route6) error {
+	return s.saveObject("route6", route6.Key, route6)
+}
+
+func (s *Storage) SavePerson(person *parser.Person) error {
+	return s.saveObject("person", person.Key, person)
+}
+
+func (s *Storage) SaveRole(role *parser.Role) error {
+	return s.saveObject("role", role.Key, role)
+}
+
+func (s *Storage) SaveMntner(mntner *parser.Mntner) error {
+	return s.saveObject("mntner", mntner.Key, mntner)
+}
+
+func (s *Storage) SaveIrt(irt *parser.Irt) error {
+	return s.saveObject("irt", irt.Key, irt)
+}
+
+func (s *Storage) SaveOrganization(org *parser.Organization) error {
+	return s.saveObject("organisation", org.Key, org)
+}
+
+func (s *Storage) SaveDomain(domain *parser.Domain) error {
+	return s.saveObject("domain", domain.Key, domain)
+}
+
+func (s *Storage) SaveAsSet(asSet *parser.AsSet) error {
+	return s.saveObject("as-set", asSet.Key, asSet)
+}
+
+func (s *Storage) SaveRouteSet(routeSet *parser.RouteSet) error {
+	return s.saveObject("route-set", routeSet.Key, routeSet)
+}
+
+func (s *Storage) SaveFilterSet(filterSet *parser.FilterSet) error {
+	return s.saveObject("filter-set", filterSet.Key, filterSet)
+}
+
+func (s *Storage) SavePeeringSet(peeringSet *parser.PeeringSet) error {
+	return s.saveObject("peering-set", peeringSet.Key, peeringSet)
+}
+
+func (s *Storage) SaveRtrSet(rtrSet *parser.RtrSet) error {
+	return s.saveObject("rtr-set", rtrSet.Key, rtrSet)
+}
+
+func (s *Storage) saveObject(class, key string, obj interface{}) error {
+	if key == "" {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal object: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, entry{class: class, key: key, value: jsonData})
+	if len(s.pending) >= batchSize {
+		return s.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered objects and swaps them in for the objects stored
+// earlier for the source. The DB stays open for other sources.
+func (s *Storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(s.source)) != nil {
+			if err := tx.DeleteBucket([]byte(s.source)); err != nil {
+				return err
+			}
+		}
+		return tx.MoveBucket([]byte(s.source), tx.Bucket(stagingBucket), nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace objects of %s: %w", s.source, err)
+	}
+	return nil
+}
+
+// Abort drops the objects written by s and keeps the objects stored earlier for the source
+func (s *Storage) Abort() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = s.pending[:0]
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		staging := tx.Bucket(stagingBucket)
+		if staging == nil || staging.Bucket([]byte(s.source)) == nil {
+			return nil
+		}
+		return staging.DeleteBucket([]byte(s.source))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to drop objects of %s: %w", s.source, err)
+	}
+	return nil
+}
+
+func (s *Storage) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		staging, err := tx.CreateBucketIfNotExists(stagingBucket)
+		if err != nil {
+			return err
+		}
+		source, err := staging.CreateBucketIfNotExists([]byte(s.source))
+		if err != nil {
+			return err
+		}
+		for _, e := range s.pending {
+			bucket, err := source.CreateBucketIfNotExists([]byte(e.class))
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(e.key), e.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write objects of %s: %w", s.source, err)
+	}
+
+	s.pending = s.pending[:0]
+	return nil
+}
@@ -0,0 +1,95 @@
+package parser
+
+import "strings"
+
+// Attribute is a single RPSL attribute with its continuation lines folded into Value
+type Attribute struct {
+	Name  string
+	Value string
+	// Comment holds the end-of-line comments stripped from Value
+	Comment string
+	// Line is the input line number the attribute starts on
+	Line int
+}
+
+// sourceLine is a physical input line together with its line number
+type sourceLine struct {
+	number int
+	text   string
+}
+
+// isComment reports whether the whole line is a comment or a dump banner line
+func isComment(line string) bool {
+	return line != "" && (line[0] == '#' || line[0] == '%')
+}
+
+// isContinuation reports whether the line continues the value of the previous attribute.
+// RPSL allows a value to be split over several lines that start with a space, a tab or '+'.
+func isContinuation(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t' || line[0] == '+')
+}
+
+// parseAttributes folds the physical lines of one object into its attributes.
+// Continuation lines are joined to the previous value with a newline, so
+// multi-line descr, address and remarks values survive intact.
+func parseAttributes(lines []sourceLine) []Attribute {
+	attrs := make([]Attribute, 0, len(lines))
+
+	for _, line := range lines {
+		if isContinuation(line.text) {
+			if len(attrs) == 0 {
+				continue
+			}
+			value, comment := stripComment(line.text[1:])
+			last := &attrs[len(attrs)-1]
+			if comment == "" {
+				last.Value = joinValue(last.Value, value)
+				continue
+			}
+			if value != "" {
+				last.Value = joinValue(last.Value, value)
+			}
+			last.Comment = joinValue(last.Comment, comment)
+			continue
+		}
+
+		name, value, ok := strings.Cut(line.text, ":")
+		if !ok {
+			continue
+		}
+
+		value, comment := stripComment(value)
+		attrs = append(attrs, Attribute{
+			Name:    strings.TrimSpace(name),
+			Value:   value,
+			Comment: comment,
+			Line:    line.number,
+		})
+	}
+
+	return attrs
+}
+
+func joinValue(value, continuation string) string {
+	if value == "" {
+		return continuation
+	}
+	return value + "\n" + continuation
+}
+
+// stripComment splits a raw value into the value itself and its end-of-line comment.
+// A '#' inside double quotes is part of the value.
+func stripComment(raw string) (string, string) {
+	quoted := false
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '"':
+			quoted = !quoted
+		case '#':
+			if !quoted {
+				return strings.TrimSpace(raw[:i]), strings.TrimSpace(raw[i+1:])
+			}
+		}
+	}
+	return strings.TrimSpace(raw), ""
+}
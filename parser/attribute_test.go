@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lines splits an object excerpt into numbered source lines, starting at 1
+func lines(text string) []sourceLine {
+	result := make([]sourceLine, 0)
+	for i, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		result = append(result, sourceLine{number: i + 1, text: line})
+	}
+	return result
+}
+
+func TestParseAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Attribute
+	}{
+		{
+			name: "ripe multi-line descr and address",
+			input: "organisation:   ORG-RIEN1-RIPE\n" +
+				"org-name:       Reseaux IP Europeens Network Coordination Centre (RIPE NCC)\n" +
+				"descr:          RIPE NCC\n" +
+				"                Amsterdam\n" +
+				"address:        P.O. Box 10096\n" +
+				"address:        1001EB\n" +
+				"                Amsterdam\n" +
+				"                NETHERLANDS\n" +
+				"source:         RIPE # Filtered\n",
+			want: []Attribute{
+				{Name: "organisation", Value: "ORG-RIEN1-RIPE", Line: 1},
+				{Name: "org-name", Value: "Reseaux IP Europeens Network Coordination Centre (RIPE NCC)", Line: 2},
+				{Name: "descr", Value: "RIPE NCC\nAmsterdam", Line: 3},
+				{Name: "address", Value: "P.O. Box 10096", Line: 5},
+				{Name: "address", Value: "1001EB\nAmsterdam\nNETHERLANDS", Line: 6},
+				{Name: "source", Value: "RIPE", Comment: "Filtered", Line: 9},
+			},
+		},
+		{
+			name: "apnic plus and tab continuations",
+			input: "inetnum:        1.0.0.0 - 1.0.0.255\n" +
+				"netname:        APNIC-LABS\n" +
+				"descr:          APNIC and Cloudflare DNS Resolver project\n" +
+				"+\n" +
+				"+               Routed globally by AS13335/Cloudflare\n" +
+				"remarks:        ---------------\n" +
+				"\tAll Cloudflare abuse reporting can be done via\n" +
+				"\tresolver-abuse@cloudflare.com\n" +
+				"country:        AU\n",
+			want: []Attribute{
+				{Name: "inetnum", Value: "1.0.0.0 - 1.0.0.255", Line: 1},
+				{Name: "netname", Value: "APNIC-LABS", Line: 2},
+				{Name: "descr", Value: "APNIC and Cloudflare DNS Resolver project\n\nRouted globally by AS13335/Cloudflare", Line: 3},
+				{Name: "remarks", Value: "---------------\nAll Cloudflare abuse reporting can be done via\nresolver-abuse@cloudflare.com", Line: 6},
+				{Name: "country", Value: "AU", Line: 9},
+			},
+		},
+		{
+			name: "colon inside a continuation line",
+			input: "aut-num:        AS3333\n" +
+				"remarks:        Peering policy\n" +
+				"                see: https://www.ripe.net/peering\n" +
+				"import:         from AS1 accept ANY\n",
+			want: []Attribute{
+				{Name: "aut-num", Value: "AS3333", Line: 1},
+				{Name: "remarks", Value: "Peering policy\nsee: https://www.ripe.net/peering", Line: 2},
+				{Name: "import", Value: "from AS1 accept ANY", Line: 4},
+			},
+		},
+		{
+			name: "continuation with no preceding attribute",
+			input: "                orphaned continuation\n" +
+				"+\n" +
+				"mntner:         RIPE-NCC-MNT\n",
+			want: []Attribute{
+				{Name: "mntner", Value: "RIPE-NCC-MNT", Line: 3},
+			},
+		},
+		{
+			name: "comments on continuation lines and quoted hashes",
+			input: "role:           Abuse Desk\n" +
+				"remarks:        \"#1 contact\" # first\n" +
+				"                second line # second\n",
+			want: []Attribute{
+				{Name: "role", Value: "Abuse Desk", Line: 1},
+				{Name: "remarks", Value: "\"#1 contact\"\nsecond line", Comment: "first\nsecond", Line: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAttributes(lines(tt.input))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAttributes() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
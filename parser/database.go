@@ -0,0 +1,151 @@
+package parser
+
+// NewRipeDatabase returns an empty database ready to be filled as a Storage
+func NewRipeDatabase() *RipeDatabase {
+	return &RipeDatabase{
+		ASNs:          make(map[string]*ASN),
+		InetNums:      make(map[string]*InetNum),
+		Inet6Nums:     make(map[string]*Inet6Num),
+		Routes:        make(map[string]*Route),
+		Routes6:       make(map[string]*Route6),
+		Persons:       make(map[string]*Person),
+		Roles:         make(map[string]*Role),
+		Mntners:       make(map[string]*Mntner),
+		Irts:          make(map[string]*Irt),
+		Organizations: make(map[string]*Organization),
+		Domains:       make(map[string]*Domain),
+		AsSets:        make(map[string]*AsSet),
+		RouteSets:     make(map[string]*RouteSet),
+		FilterSets:    make(map[string]*FilterSet),
+		PeeringSets:   make(map[string]*PeeringSet),
+		RtrSets:       make(map[string]*RtrSet),
+	}
+}
+
+func (db *RipeDatabase) SaveASN(asn *ASN) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.ASNs[asn.Key] = asn
+	return nil
+}
+
+func (db *RipeDatabase) SaveInetNum(inetNum *InetNum) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.InetNums[inetNum.Key] = inetNum
+	return nil
+}
+
+func (db *RipeDatabase) SaveInet6Num(inet6Num *Inet6Num) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Inet6Nums[inet6Num.Key] = inet6Num
+	return nil
+}
+
+func (db *RipeDatabase) SaveRoute(route *Route) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Routes[route.Key] = route
+	return nil
+}
+
+func (db *RipeDatabase) SaveRoute6(route6 *Route6) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Routes6[route6.Key] = route6
+	return nil
+}
+
+func (db *RipeDatabase) SavePerson(person *Person) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Persons[person.Key] = person
+	return nil
+}
+
+func (db *RipeDatabase) SaveRole(role *Role) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Roles[role.Key] = role
+	return nil
+}
+
+func (db *RipeDatabase) SaveMntner(mntner *Mntner) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Mntners[mntner.Key] = mntner
+	return nil
+}
+
+func (db *RipeDatabase) SaveIrt(irt *Irt) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Irts[irt.Key] = irt
+	return nil
+}
+
+func (db *RipeDatabase) SaveOrganization(org *Organization) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Organizations[org.Key] = org
+	return nil
+}
+
+func (db *RipeDatabase) SaveDomain(domain *Domain) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Domains[domain.Key] = domain
+	return nil
+}
+
+func (db *RipeDatabase) SaveAsSet(asSet *AsSet) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.AsSets[asSet.Key] = asSet
+	return nil
+}
+
+func (db *RipeDatabase) SaveRouteSet(routeSet *RouteSet) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.RouteSets[routeSet.Key] = routeSet
+	return nil
+}
+
+func (db *RipeDatabase) SaveFilterSet(filterSet *FilterSet) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.FilterSets[filterSet.Key] = filterSet
+	return nil
+}
+
+func (db *RipeDatabase) SavePeeringSet(peeringSet *PeeringSet) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.PeeringSets[peeringSet.Key] = peeringSet
+	return nil
+}
+
+func (db *RipeDatabase) SaveRtrSet(rtrSet *RtrSet) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.RtrSets[rtrSet.Key] = rtrSet
+	return nil
+}
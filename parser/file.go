@@ -3,6 +3,7 @@ package parser
 import (
 	"bufio"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -14,16 +15,28 @@ const (
 )
 
 func (p *Parser) ParseFile(filename string) error {
+	return p.ParseFileContext(context.Background(), filename)
+}
+
+// ParseFileContext is ParseFile that stops before the next object once ctx is done
+// and returns ctx.Err(). Objects saved before that are left in the storage.
+func (p *Parser) ParseFileContext(ctx context.Context, filename string) error {
 	file, err := os.Open(filename)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	return p.parseFromReader(file)
+	return p.parseFromReader(ctx, file)
 }
 
 func (p *Parser) ParseGZFile(gzFilePath string) error {
+	return p.ParseGZFileContext(context.Background(), gzFilePath)
+}
+
+// ParseGZFileContext is ParseGZFile that stops before the next object once ctx is done
+// and returns ctx.Err()
+func (p *Parser) ParseGZFileContext(ctx context.Context, gzFilePath string) error {
 	gzFile, err := os.Open(gzFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to open gzip file %s: %w", gzFilePath, err)
@@ -36,50 +49,57 @@ func (p *Parser) ParseGZFile(gzFilePath string) error {
 	}
 	defer gzReader.Close()
 
-	return p.parseFromReader(gzReader)
+	return p.parseFromReader(ctx, gzReader)
 }
 
-func (p *Parser) parseFromReader(reader io.Reader) error {
-	var currentObject []string
-	var currentType string
+func (p *Parser) parseFromReader(ctx context.Context, reader io.Reader) error {
+	var currentObject []sourceLine
+	lineNumber := 0
 
 	scanner := bufio.NewScanner(reader)
 	buf := make([]byte, 0, bufferSize)
 	scanner.Buffer(buf, bufferSize)
 
 	for scanner.Scan() {
+		lineNumber++
 		line := scanner.Text()
+		blank := strings.TrimSpace(line) == ""
+
+		// Comment and banner lines neither start nor end an object
+		if isComment(line) {
+			if p.onComment != nil {
+				p.onComment(line)
+			}
+			continue
+		}
 
 		// Skip empty lines at the start
-		if len(currentObject) == 0 && line == "" {
+		if len(currentObject) == 0 && blank {
 			continue
 		}
 
 		// If we hit an empty line and have content, parse the current object
-		if line == "" && len(currentObject) > 0 {
-			if err := p.parseAndSaveObject(currentType, currentObject); err != nil {
-				return fmt.Errorf("failed to parse object: %w", err)
+		if blank && len(currentObject) > 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := p.parseAndSaveObject(newObject(currentObject)); err != nil {
+				return fmt.Errorf("failed to parse object at line %d: %w", currentObject[0].number, err)
 			}
 			currentObject = nil
-			currentType = ""
 			continue
 		}
 
-		// If this is the first line of a new object, determine its type
-		if len(currentObject) == 0 {
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				currentType = strings.TrimSpace(parts[0])
-			}
-		}
-
-		currentObject = append(currentObject, line)
+		currentObject = append(currentObject, sourceLine{number: lineNumber, text: line})
 	}
 
 	// Parse the last object if there is one
 	if len(currentObject) > 0 {
-		if err := p.parseAndSaveObject(currentType, currentObject); err != nil {
-			return fmt.Errorf("failed to parse object: %w", err)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := p.parseAndSaveObject(newObject(currentObject)); err != nil {
+			return fmt.Errorf("failed to parse object at line %d: %w", currentObject[0].number, err)
 		}
 	}
 	return scanner.Err()
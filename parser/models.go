@@ -1,6 +1,9 @@
 package parser
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // BaseObject contains common fields for all RIPE objects
 type BaseObject struct {
@@ -8,9 +11,13 @@ type BaseObject struct {
 	Created      time.Time
 	LastModified time.Time
 	Source       string
-	AdminC       string
-	TechC        string
+	AdminC       []string
+	TechC        []string
 	MntBy        []string
+	// Org is the organisation the object references, any class may have one
+	Org string
+	// Object is the generic object the typed model was built from
+	Object *Object `json:"-"`
 }
 
 // ASN represents an Autonomous System Number object
@@ -19,9 +26,10 @@ type ASN struct {
 	ASNumber    string
 	ASName      string
 	Description []string
-	Org         string
+	AbuseC      string
 	Status      string
-	Notify      string
+	Notify      []string
+	MemberOf    []string
 }
 
 // InetNum represents an IP address range object
@@ -32,25 +40,38 @@ type InetNum struct {
 	Description []string
 	Country     string
 	Status      string
-	Org         string
+	AbuseC      string
+	MntIrt      []string
+}
+
+// Inet6Num represents an IPv6 address range object
+type Inet6Num struct {
+	BaseObject
+	Prefix      string
+	NetName     string
+	Description []string
+	Country     string
+	Status      string
+	AbuseC      string
+	MntIrt      []string
 }
 
 // Route represents a route object
 type Route struct {
 	BaseObject
 	Prefix      string
-	Description string
+	Description []string
 	Origin      string
-	Org         string
+	MemberOf    []string
 }
 
 // Route6 represents an IPv6 route object
 type Route6 struct {
 	BaseObject
 	Prefix      string
-	Description string
+	Description []string
 	Origin      string
-	Org         string
+	MemberOf    []string
 }
 
 // Person represents a person object
@@ -58,18 +79,52 @@ type Person struct {
 	BaseObject
 	Name    string
 	Address []string
-	Phone   string
-	Email   string
+	Phone   []string
+	Email   []string
 	NicHdl  string
 }
 
+// Role represents a role object, a contact shared by several people
+type Role struct {
+	BaseObject
+	Name         string
+	Address      []string
+	Phone        []string
+	Email        []string
+	AbuseMailbox string
+	NicHdl       string
+}
+
+// Mntner represents a maintainer object. Auth keeps only the scheme of password hashes.
+type Mntner struct {
+	BaseObject
+	Mntner       string
+	Description  []string
+	UpdTo        []string
+	MntNfy       []string
+	Auth         []string
+	AbuseMailbox string
+}
+
+// Irt represents an incident response team object
+type Irt struct {
+	BaseObject
+	Irt          string
+	Address      []string
+	Phone        []string
+	Email        []string
+	AbuseMailbox string
+	IrtNfy       []string
+	Auth         []string
+}
+
 // Organization represents an organization object
 type Organization struct {
 	BaseObject
 	Name    string
 	Type    string
 	Address []string
-	Email   string
+	Email   []string
 	AbuseC  string
 	OrgID   string
 }
@@ -78,18 +133,76 @@ type Organization struct {
 type Domain struct {
 	BaseObject
 	Domain      string
-	Description string
+	Description []string
 	Nameservers []string
-	ZoneC       string
+	ZoneC       []string
+}
+
+// AsSet represents an as-set object, a named set of ASNs and other as-sets
+type AsSet struct {
+	BaseObject
+	Name        string
+	Description []string
+	Members     []string
+	MbrsByRef   []string
+}
+
+// RouteSet represents a route-set object, a named set of prefixes and other sets
+type RouteSet struct {
+	BaseObject
+	Name        string
+	Description []string
+	Members     []string
+	MpMembers   []string
+	MbrsByRef   []string
+}
+
+// FilterSet represents a filter-set object
+type FilterSet struct {
+	BaseObject
+	Name        string
+	Description []string
+	Filter      string
+	MpFilter    string
+}
+
+// PeeringSet represents a peering-set object
+type PeeringSet struct {
+	BaseObject
+	Name        string
+	Description []string
+	Peering     []string
+	MpPeering   []string
+}
+
+// RtrSet represents an rtr-set object, a named set of routers
+type RtrSet struct {
+	BaseObject
+	Name        string
+	Description []string
+	Members     []string
+	MpMembers   []string
+	MbrsByRef   []string
 }
 
-// RipeDatabase represents the complete database
+// RipeDatabase represents the complete database. It implements Storage,
+// keeping every object in memory under its primary key.
 type RipeDatabase struct {
+	mu            sync.Mutex
 	ASNs          map[string]*ASN
 	InetNums      map[string]*InetNum
+	Inet6Nums     map[string]*Inet6Num
 	Routes        map[string]*Route
 	Routes6       map[string]*Route6
 	Persons       map[string]*Person
+	Roles         map[string]*Role
+	Mntners       map[string]*Mntner
+	Irts          map[string]*Irt
 	Organizations map[string]*Organization
 	Domains       map[string]*Domain
+	AsSets        map[string]*AsSet
+	RouteSets     map[string]*RouteSet
+	FilterSets    map[string]*FilterSet
+	PeeringSets   map[string]*PeeringSet
+	RtrSets       map[string]*RtrSet
 }
@@ -0,0 +1,60 @@
+package parser
+
+// Object is a generic RPSL object. It keeps every attribute in input order,
+// including repeated ones, so nothing is lost when a typed model has no field for it.
+type Object struct {
+	Class      string
+	Attributes []Attribute
+}
+
+func newObject(lines []sourceLine) *Object {
+	obj := &Object{
+		Attributes: parseAttributes(lines),
+	}
+	if len(obj.Attributes) > 0 {
+		obj.Class = obj.Attributes[0].Name
+	}
+	return obj
+}
+
+// Key returns the value of the class attribute, which names the object
+func (o *Object) Key() string {
+	if len(o.Attributes) == 0 {
+		return ""
+	}
+	return o.Attributes[0].Value
+}
+
+// PrimaryKey returns the RPSL primary key of the object. Most classes are keyed
+// by their class attribute, route and route6 by prefix and origin together
+// (as in "193.0.0.0/21AS3333"), person and role by their nic-hdl.
+func (o *Object) PrimaryKey() string {
+	switch o.Class {
+	case "route", "route6":
+		return o.Key() + o.Attr("origin")
+	case "person", "role":
+		return o.Attr("nic-hdl")
+	}
+	return o.Key()
+}
+
+// Attr returns the first value of the named attribute or an empty string
+func (o *Object) Attr(name string) string {
+	for _, attr := range o.Attributes {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Values returns every value of the named attribute in input order
+func (o *Object) Values(name string) []string {
+	values := make([]string, 0)
+	for _, attr := range o.Attributes {
+		if attr.Name == name {
+			values = append(values, attr.Value)
+		}
+	}
+	return values
+}
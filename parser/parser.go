@@ -12,15 +12,25 @@ const (
 type Storage interface {
 	SaveASN(asn *ASN) error
 	SaveInetNum(inetNum *InetNum) error
+	SaveInet6Num(inet6Num *Inet6Num) error
 	SaveRoute(route *Route) error
 	SaveRoute6(route6 *Route6) error
 	SavePerson(person *Person) error
+	SaveRole(role *Role) error
+	SaveMntner(mntner *Mntner) error
+	SaveIrt(irt *Irt) error
 	SaveOrganization(org *Organization) error
 	SaveDomain(domain *Domain) error
+	SaveAsSet(asSet *AsSet) error
+	SaveRouteSet(routeSet *RouteSet) error
+	SaveFilterSet(filterSet *FilterSet) error
+	SavePeeringSet(peeringSet *PeeringSet) error
+	SaveRtrSet(rtrSet *RtrSet) error
 }
 
 type Parser struct {
-	storage Storage
+	storage   Storage
+	onComment func(comment string)
 }
 
 func NewParser(storage Storage) *Parser {
@@ -29,310 +39,362 @@ func NewParser(storage Storage) *Parser {
 	}
 }
 
-func (p *Parser) parseAndSaveObject(objType string, lines []string) error {
-	base := p.parseBaseObject(lines)
+// OnComment registers a callback that receives every full-line comment
+// ('#' or '%') dropped from the input, including dump banners.
+func (p *Parser) OnComment(fn func(comment string)) {
+	p.onComment = fn
+}
+
+func (p *Parser) parseAndSaveObject(obj *Object) error {
+	redactObject(obj)
+	base := p.parseBaseObject(obj)
 
-	switch objType {
+	switch obj.Class {
 	case "aut-num":
-		asn, err := p.parseASN(base, lines)
+		asn, err := p.parseASN(base, obj)
 		if err != nil {
 			return err
 		}
 		return p.storage.SaveASN(asn)
 	case "inetnum":
-		inetnum, err := p.parseInetNum(base, lines)
+		inetnum, err := p.parseInetNum(base, obj)
 		if err != nil {
 			return err
 		}
 		return p.storage.SaveInetNum(inetnum)
+	case "inet6num":
+		inet6num, err := p.parseInet6Num(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SaveInet6Num(inet6num)
 	case "route":
-		route, err := p.parseRoute(base, lines)
+		route, err := p.parseRoute(base, obj)
 		if err != nil {
 			return err
 		}
 		return p.storage.SaveRoute(route)
 	case "route6":
-		route6, err := p.parseRoute6(base, lines)
+		route6, err := p.parseRoute6(base, obj)
 		if err != nil {
 			return err
 		}
 		return p.storage.SaveRoute6(route6)
 	case "person":
-		person, err := p.parsePerson(base, lines)
+		person, err := p.parsePerson(base, obj)
 		if err != nil {
 			return err
 		}
 		return p.storage.SavePerson(person)
+	case "role":
+		role, err := p.parseRole(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SaveRole(role)
+	case "mntner":
+		mntner, err := p.parseMntner(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SaveMntner(mntner)
+	case "irt":
+		irt, err := p.parseIrt(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SaveIrt(irt)
 	case "organisation":
-		org, err := p.parseOrganization(base, lines)
+		org, err := p.parseOrganization(base, obj)
 		if err != nil {
 			return err
 		}
 		return p.storage.SaveOrganization(org)
 	case "domain":
-		domain, err := p.parseDomain(base, lines)
+		domain, err := p.parseDomain(base, obj)
 		if err != nil {
 			return err
 		}
 		return p.storage.SaveDomain(domain)
+	case "as-set":
+		asSet, err := p.parseAsSet(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SaveAsSet(asSet)
+	case "route-set":
+		routeSet, err := p.parseRouteSet(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SaveRouteSet(routeSet)
+	case "filter-set":
+		filterSet, err := p.parseFilterSet(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SaveFilterSet(filterSet)
+	case "peering-set":
+		peeringSet, err := p.parsePeeringSet(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SavePeeringSet(peeringSet)
+	case "rtr-set":
+		rtrSet, err := p.parseRtrSet(base, obj)
+		if err != nil {
+			return err
+		}
+		return p.storage.SaveRtrSet(rtrSet)
 	}
 
 	return nil
 }
 
-func (p *Parser) parseBaseObject(lines []string) BaseObject {
+func (p *Parser) parseBaseObject(obj *Object) BaseObject {
 	base := BaseObject{
-		MntBy: make([]string, 0),
+		Key:    obj.PrimaryKey(),
+		Source: obj.Attr("source"),
+		AdminC: obj.Values("admin-c"),
+		TechC:  obj.Values("tech-c"),
+		MntBy:  obj.Values("mnt-by"),
+		Org:    obj.Attr("org"),
+		Object: obj,
 	}
 
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "Key":
-			base.Key = value
-		case "created":
-			if t, err := time.Parse(TimeLayout, value); err == nil {
-				base.Created = t
-			}
-		case "last-modified":
-			if t, err := time.Parse(TimeLayout, value); err == nil {
-				base.LastModified = t
-			}
-		case "source":
-			base.Source = value
-		case "admin-c":
-			base.AdminC = value
-		case "tech-c":
-			base.TechC = value
-		case "mnt-by":
-			base.MntBy = append(base.MntBy, value)
-		}
+	if t, err := time.Parse(TimeLayout, obj.Attr("created")); err == nil {
+		base.Created = t
+	}
+	if t, err := time.Parse(TimeLayout, obj.Attr("last-modified")); err == nil {
+		base.LastModified = t
 	}
 
 	return base
 }
 
-func (p *Parser) parseASN(base BaseObject, lines []string) (*ASN, error) {
-	asn := &ASN{
+func (p *Parser) parseASN(base BaseObject, obj *Object) (*ASN, error) {
+	return &ASN{
 		BaseObject:  base,
-		Description: make([]string, 0),
-	}
+		ASNumber:    obj.Attr("aut-num"),
+		ASName:      obj.Attr("as-name"),
+		Description: obj.Values("descr"),
+		AbuseC:      obj.Attr("abuse-c"),
+		Status:      obj.Attr("status"),
+		Notify:      obj.Values("notify"),
+		MemberOf:    splitList(obj.Values("member-of")),
+	}, nil
+}
 
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
+func (p *Parser) parseInetNum(base BaseObject, obj *Object) (*InetNum, error) {
+	return &InetNum{
+		BaseObject:  base,
+		IPRange:     obj.Attr("inetnum"),
+		NetName:     obj.Attr("netname"),
+		Description: obj.Values("descr"),
+		Country:     obj.Attr("country"),
+		Status:      obj.Attr("status"),
+		AbuseC:      obj.Attr("abuse-c"),
+		MntIrt:      obj.Values("mnt-irt"),
+	}, nil
+}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "aut-num":
-			asn.ASNumber = value
-		case "as-name":
-			asn.ASName = value
-		case "descr":
-			asn.Description = append(asn.Description, value)
-		case "org":
-			asn.Org = value
-		case "status":
-			asn.Status = value
-		case "notify":
-			asn.Notify = value
-		}
-	}
+func (p *Parser) parseInet6Num(base BaseObject, obj *Object) (*Inet6Num, error) {
+	return &Inet6Num{
+		BaseObject:  base,
+		Prefix:      obj.Attr("inet6num"),
+		NetName:     obj.Attr("netname"),
+		Description: obj.Values("descr"),
+		Country:     obj.Attr("country"),
+		Status:      obj.Attr("status"),
+		AbuseC:      obj.Attr("abuse-c"),
+		MntIrt:      obj.Values("mnt-irt"),
+	}, nil
+}
 
-	return asn, nil
+func (p *Parser) parseRoute(base BaseObject, obj *Object) (*Route, error) {
+	return &Route{
+		BaseObject:  base,
+		Prefix:      obj.Attr("route"),
+		Description: obj.Values("descr"),
+		Origin:      obj.Attr("origin"),
+		MemberOf:    splitList(obj.Values("member-of")),
+	}, nil
 }
 
-func (p *Parser) parseInetNum(base BaseObject, lines []string) (*InetNum, error) {
-	inetNum := &InetNum{
+func (p *Parser) parseRoute6(base BaseObject, obj *Object) (*Route6, error) {
+	return &Route6{
 		BaseObject:  base,
-		Description: make([]string, 0),
-	}
+		Prefix:      obj.Attr("route6"),
+		Description: obj.Values("descr"),
+		Origin:      obj.Attr("origin"),
+		MemberOf:    splitList(obj.Values("member-of")),
+	}, nil
+}
 
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
+func (p *Parser) parsePerson(base BaseObject, obj *Object) (*Person, error) {
+	return &Person{
+		BaseObject: base,
+		Name:       obj.Attr("person"),
+		Address:    obj.Values("address"),
+		Phone:      obj.Values("phone"),
+		Email:      obj.Values("e-mail"),
+		NicHdl:     obj.Attr("nic-hdl"),
+	}, nil
+}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "inetnum":
-			inetNum.IPRange = value
-		case "netname":
-			inetNum.NetName = value
-		case "descr":
-			inetNum.Description = append(inetNum.Description, value)
-		case "country":
-			inetNum.Country = value
-		case "status":
-			inetNum.Status = value
-		case "org":
-			inetNum.Org = value
-		}
-	}
+func (p *Parser) parseRole(base BaseObject, obj *Object) (*Role, error) {
+	return &Role{
+		BaseObject:   base,
+		Name:         obj.Attr("role"),
+		Address:      obj.Values("address"),
+		Phone:        obj.Values("phone"),
+		Email:        obj.Values("e-mail"),
+		AbuseMailbox: obj.Attr("abuse-mailbox"),
+		NicHdl:       obj.Attr("nic-hdl"),
+	}, nil
+}
 
-	return inetNum, nil
+func (p *Parser) parseMntner(base BaseObject, obj *Object) (*Mntner, error) {
+	return &Mntner{
+		BaseObject:   base,
+		Mntner:       obj.Attr("mntner"),
+		Description:  obj.Values("descr"),
+		UpdTo:        obj.Values("upd-to"),
+		MntNfy:       obj.Values("mnt-nfy"),
+		Auth:         redactAuth(obj.Values("auth")),
+		AbuseMailbox: obj.Attr("abuse-mailbox"),
+	}, nil
 }
 
-func (p *Parser) parseRoute(base BaseObject, lines []string) (*Route, error) {
-	route := &Route{BaseObject: base}
+func (p *Parser) parseIrt(base BaseObject, obj *Object) (*Irt, error) {
+	return &Irt{
+		BaseObject:   base,
+		Irt:          obj.Attr("irt"),
+		Address:      obj.Values("address"),
+		Phone:        obj.Values("phone"),
+		Email:        obj.Values("e-mail"),
+		AbuseMailbox: obj.Attr("abuse-mailbox"),
+		IrtNfy:       obj.Values("irt-nfy"),
+		Auth:         redactAuth(obj.Values("auth")),
+	}, nil
+}
 
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
+func (p *Parser) parseOrganization(base BaseObject, obj *Object) (*Organization, error) {
+	return &Organization{
+		BaseObject: base,
+		OrgID:      obj.Attr("organisation"),
+		Name:       obj.Attr("org-name"),
+		Type:       obj.Attr("org-type"),
+		Address:    obj.Values("address"),
+		Email:      obj.Values("e-mail"),
+		AbuseC:     obj.Attr("abuse-c"),
+	}, nil
+}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "route":
-			route.Prefix = value
-		case "descr":
-			route.Description = value
-		case "origin":
-			route.Origin = value
-		case "org":
-			route.Org = value
-		}
-	}
+func (p *Parser) parseDomain(base BaseObject, obj *Object) (*Domain, error) {
+	return &Domain{
+		BaseObject:  base,
+		Domain:      obj.Attr("domain"),
+		Description: obj.Values("descr"),
+		Nameservers: obj.Values("nserver"),
+		ZoneC:       obj.Values("zone-c"),
+	}, nil
+}
 
-	return route, nil
+func (p *Parser) parseAsSet(base BaseObject, obj *Object) (*AsSet, error) {
+	return &AsSet{
+		BaseObject:  base,
+		Name:        obj.Attr("as-set"),
+		Description: obj.Values("descr"),
+		Members:     splitList(obj.Values("members")),
+		MbrsByRef:   splitList(obj.Values("mbrs-by-ref")),
+	}, nil
 }
 
-func (p *Parser) parseRoute6(base BaseObject, lines []string) (*Route6, error) {
-	route6 := &Route6{BaseObject: base}
+func (p *Parser) parseRouteSet(base BaseObject, obj *Object) (*RouteSet, error) {
+	return &RouteSet{
+		BaseObject:  base,
+		Name:        obj.Attr("route-set"),
+		Description: obj.Values("descr"),
+		Members:     splitList(obj.Values("members")),
+		MpMembers:   splitList(obj.Values("mp-members")),
+		MbrsByRef:   splitList(obj.Values("mbrs-by-ref")),
+	}, nil
+}
 
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
+func (p *Parser) parseFilterSet(base BaseObject, obj *Object) (*FilterSet, error) {
+	return &FilterSet{
+		BaseObject:  base,
+		Name:        obj.Attr("filter-set"),
+		Description: obj.Values("descr"),
+		Filter:      obj.Attr("filter"),
+		MpFilter:    obj.Attr("mp-filter"),
+	}, nil
+}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "route6":
-			route6.Prefix = value
-		case "descr":
-			route6.Description = value
-		case "origin":
-			route6.Origin = value
-		case "org":
-			route6.Org = value
-		}
-	}
+func (p *Parser) parsePeeringSet(base BaseObject, obj *Object) (*PeeringSet, error) {
+	return &PeeringSet{
+		BaseObject:  base,
+		Name:        obj.Attr("peering-set"),
+		Description: obj.Values("descr"),
+		Peering:     obj.Values("peering"),
+		MpPeering:   obj.Values("mp-peering"),
+	}, nil
+}
 
-	return route6, nil
+func (p *Parser) parseRtrSet(base BaseObject, obj *Object) (*RtrSet, error) {
+	return &RtrSet{
+		BaseObject:  base,
+		Name:        obj.Attr("rtr-set"),
+		Description: obj.Values("descr"),
+		Members:     splitList(obj.Values("members")),
+		MpMembers:   splitList(obj.Values("mp-members")),
+		MbrsByRef:   splitList(obj.Values("mbrs-by-ref")),
+	}, nil
 }
 
-func (p *Parser) parsePerson(base BaseObject, lines []string) (*Person, error) {
-	person := &Person{
-		BaseObject: base,
-		Address:    make([]string, 0),
+// splitList flattens list attributes such as members, where every value
+// may hold several comma separated items spread over continuation lines.
+func splitList(values []string) []string {
+	items := make([]string, 0, len(values))
+	for _, value := range values {
+		items = append(items, strings.FieldsFunc(value, isListSeparator)...)
 	}
+	return items
+}
 
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
+func isListSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t' || r == '\n'
+}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "person":
-			person.Name = value
-		case "address":
-			person.Address = append(person.Address, value)
-		case "phone":
-			person.Phone = value
-		case "e-mail":
-			person.Email = value
-		case "nic-hdl":
-			person.NicHdl = value
-		}
+// redactAuth drops credentials from auth values and keeps only their scheme.
+// Key references such as PGPKEY-xxx and X509-xxx are public and kept as is.
+func redactAuth(values []string) []string {
+	redacted := make([]string, 0, len(values))
+	for _, value := range values {
+		redacted = append(redacted, redactAuthValue(value))
 	}
-
-	return person, nil
+	return redacted
 }
 
-func (p *Parser) parseOrganization(base BaseObject, lines []string) (*Organization, error) {
-	org := &Organization{
-		BaseObject: base,
-		Address:    make([]string, 0),
+func redactAuthValue(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return value
 	}
-
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "organisation":
-			org.OrgID = value
-		case "org-name":
-			org.Name = value
-		case "org-type":
-			org.Type = value
-		case "address":
-			org.Address = append(org.Address, value)
-		case "e-mail":
-			org.Email = value
-		case "abuse-c":
-			org.AbuseC = value
-		}
+	switch scheme := strings.ToUpper(fields[0]); scheme {
+	case "MD5-PW", "BCRYPT-PW", "CRYPT-PW", "SSO":
+		return scheme
 	}
-
-	return org, nil
+	return value
 }
 
-func (p *Parser) parseDomain(base BaseObject, lines []string) (*Domain, error) {
-	domain := &Domain{
-		BaseObject:  base,
-		Nameservers: make([]string, 0),
-	}
-
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "domain":
-			domain.Domain = value
-		case "descr":
-			domain.Description = value
-		case "nserver":
-			domain.Nameservers = append(domain.Nameservers, value)
-		case "zone-c":
-			domain.ZoneC = value
+// redactObject redacts the auth attributes of the generic object as well, since it
+// stays reachable through BaseObject.Object
+func redactObject(obj *Object) {
+	for i := range obj.Attributes {
+		if obj.Attributes[i].Name == "auth" {
+			obj.Attributes[i].Value = redactAuthValue(obj.Attributes[i].Value)
 		}
 	}
-
-	return domain, nil
 }
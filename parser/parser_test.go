@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactAuth(t *testing.T) {
+	db := NewRipeDatabase()
+	input := "mntner:         EXAMPLE-MNT\n" +
+		"auth:           MD5-PW\t$1$abc$secret\n" +
+		"auth:           bcrypt-pw $2a$10$secret\n" +
+		"auth:           SSO user@example.net\n" +
+		"auth:           PGPKEY-A1B2C3D4\n" +
+		"auth:\n" +
+		"source:         RIPE\n"
+	if err := NewParser(db).parseFromReader(t.Context(), strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	mntner := db.Mntners["EXAMPLE-MNT"]
+	if mntner == nil {
+		t.Fatal("mntner not saved")
+	}
+	want := []string{"MD5-PW", "BCRYPT-PW", "SSO", "PGPKEY-A1B2C3D4", ""}
+	if strings.Join(mntner.Auth, "|") != strings.Join(want, "|") {
+		t.Errorf("Auth = %q, want %q", mntner.Auth, want)
+	}
+	if got := mntner.Object.Values("auth"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Object auth = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+package rirs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+)
+
+type PrefixListFormat int
+
+const (
+	PrefixListPlain PrefixListFormat = iota
+	PrefixListCisco
+	PrefixListJuniper
+	PrefixListBird
+)
+
+var (
+	ErrEmptyPrefixList = errors.New("empty prefix list")
+	ErrMixedFamilies   = errors.New("prefix list mixes IPv4 and IPv6")
+)
+
+// PrefixRange is a prefix together with the lengths a filter accepts under it,
+// as in "10.0.0.0/23 ge 24 le 24". An exact prefix has Min and Max equal to its length.
+type PrefixRange struct {
+	Prefix netip.Prefix
+	Min    int
+	Max    int
+}
+
+// IsExact reports whether the range only matches Prefix itself
+func (p PrefixRange) IsExact() bool {
+	return p.Min == p.Prefix.Bits() && p.Max == p.Prefix.Bits()
+}
+
+// String returns the range in RPSL notation, such as "10.0.0.0/23^24-24"
+func (p PrefixRange) String() string {
+	if p.IsExact() {
+		return p.Prefix.String()
+	}
+	return fmt.Sprintf("%s^%d-%d", p.Prefix, p.Min, p.Max)
+}
+
+// Exact turns prefixes into ranges that match each prefix only
+func Exact(prefixes []netip.Prefix) []PrefixRange {
+	ranges := make([]PrefixRange, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		ranges = append(ranges, PrefixRange{Prefix: prefix.Masked(), Min: prefix.Bits(), Max: prefix.Bits()})
+	}
+	return ranges
+}
+
+// PrefixList returns the prefixes of all synced sources for query, see SetIndex.PrefixList
+func (r *rir) PrefixList(ctx context.Context, query string) ([]netip.Prefix, error) {
+	return r.sets.PrefixList(ctx, query)
+}
+
+// PrefixList returns every route and route6 prefix originated by an ASN
+// or by any ASN of an as-set, sorted and without duplicates.
+func (idx *SetIndex) PrefixList(ctx context.Context, query string) ([]netip.Prefix, error) {
+	asns, err := idx.Expand(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	seen := make(map[netip.Prefix]struct{})
+	prefixes := make([]netip.Prefix, 0)
+	for _, asn := range asns {
+		for _, prefix := range idx.routesByOrigin[asn] {
+			if _, ok := seen[prefix]; ok {
+				continue
+			}
+			seen[prefix] = struct{}{}
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sortPrefixes(prefixes)
+
+	return prefixes, nil
+}
+
+// Aggregate shortens a prefix list the way bgpq4 -A does. Prefixes inside a less
+// specific one are folded into it by raising its maximum length, and the two halves
+// of a prefix with the same length range are merged into it, so every input prefix
+// is still matched. A folded range also matches unregistered prefixes of the lengths it spans.
+func Aggregate(prefixes []netip.Prefix) []PrefixRange {
+	sorted := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		sorted = append(sorted, prefix.Masked())
+	}
+	sortPrefixes(sorted)
+
+	aggregated := make([]PrefixRange, 0, len(sorted))
+	for _, prefix := range sorted {
+		// Sorted order puts a covering prefix before everything it contains
+		if n := len(aggregated); n > 0 && aggregated[n-1].Prefix.Overlaps(prefix) {
+			last := &aggregated[n-1]
+			last.Min = min(last.Min, prefix.Bits())
+			last.Max = max(last.Max, prefix.Bits())
+			continue
+		}
+		aggregated = append(aggregated, PrefixRange{Prefix: prefix, Min: prefix.Bits(), Max: prefix.Bits()})
+
+		for len(aggregated) > 1 {
+			n := len(aggregated)
+			parent, ok := mergeSiblings(aggregated[n-2], aggregated[n-1])
+			if !ok {
+				break
+			}
+			aggregated = append(aggregated[:n-2], parent)
+		}
+	}
+
+	return aggregated
+}
+
+// mergeSiblings returns the parent of a and b when they are the two halves of it
+// and accept the same lengths
+func mergeSiblings(a, b PrefixRange) (PrefixRange, bool) {
+	if a.Min != b.Min || a.Max != b.Max {
+		return PrefixRange{}, false
+	}
+	pa, pb := a.Prefix, b.Prefix
+	if pa.Bits() != pb.Bits() || pa.Bits() == 0 || pa == pb {
+		return PrefixRange{}, false
+	}
+	parentA := netip.PrefixFrom(pa.Addr(), pa.Bits()-1).Masked()
+	parentB := netip.PrefixFrom(pb.Addr(), pb.Bits()-1).Masked()
+	if parentA != parentB {
+		return PrefixRange{}, false
+	}
+	return PrefixRange{Prefix: parentA, Min: a.Min, Max: a.Max}, true
+}
+
+func sortPrefixes(prefixes []netip.Prefix) {
+	sort.Slice(prefixes, func(i, j int) bool {
+		if c := prefixes[i].Addr().Compare(prefixes[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return prefixes[i].Bits() < prefixes[j].Bits()
+	})
+}
+
+// SplitFamilies separates IPv4 and IPv6 ranges, keeping their order
+func SplitFamilies(ranges []PrefixRange) (v4, v6 []PrefixRange) {
+	for _, r := range ranges {
+		if r.Prefix.Addr().Is4() {
+			v4 = append(v4, r)
+		} else {
+			v6 = append(v6, r)
+		}
+	}
+	return v4, v6
+}
+
+// WritePrefixList renders prefix ranges as a named filter in the given format:
+// a prefix-list with ge/le for Cisco, a route-filter-list for Juniper, since its
+// prefix-lists cannot carry lengths, and a prefix set for BIRD.
+// BIRD sets cannot mix address families or be empty: a mixed list returns
+// ErrMixedFamilies, see SplitFamilies, and an empty one ErrEmptyPrefixList.
+func WritePrefixList(w io.Writer, format PrefixListFormat, name string, ranges []PrefixRange) error {
+	bw := bufio.NewWriter(w)
+
+	switch format {
+	case PrefixListPlain:
+		for _, r := range ranges {
+			fmt.Fprintln(bw, r)
+		}
+	case PrefixListCisco:
+		fmt.Fprintf(bw, "no ip prefix-list %s\n", name)
+		fmt.Fprintf(bw, "no ipv6 prefix-list %s\n", name)
+		for _, r := range ranges {
+			family := "ip"
+			if r.Prefix.Addr().Is6() {
+				family = "ipv6"
+			}
+			fmt.Fprintf(bw, "%s prefix-list %s permit %s", family, name, r.Prefix)
+			// ge must be longer than the prefix itself
+			if r.Min > r.Prefix.Bits() {
+				fmt.Fprintf(bw, " ge %d", r.Min)
+			}
+			if r.Max > r.Prefix.Bits() {
+				fmt.Fprintf(bw, " le %d", r.Max)
+			}
+			fmt.Fprintln(bw)
+		}
+	case PrefixListJuniper:
+		fmt.Fprintf(bw, "policy-options {\n    replace:\n    route-filter-list %s {\n", name)
+		for _, r := range ranges {
+			switch {
+			case r.IsExact():
+				fmt.Fprintf(bw, "        %s exact;\n", r.Prefix)
+			case r.Min == r.Prefix.Bits():
+				fmt.Fprintf(bw, "        %s upto /%d;\n", r.Prefix, r.Max)
+			default:
+				fmt.Fprintf(bw, "        %s prefix-length-range /%d-/%d;\n", r.Prefix, r.Min, r.Max)
+			}
+		}
+		fmt.Fprint(bw, "    }\n}\n")
+	case PrefixListBird:
+		if len(ranges) == 0 {
+			return fmt.Errorf("%w: BIRD has no empty prefix set for %s", ErrEmptyPrefixList, name)
+		}
+		if v4, v6 := SplitFamilies(ranges); len(v4) > 0 && len(v6) > 0 {
+			return fmt.Errorf("%w: BIRD prefix set %s must hold one family", ErrMixedFamilies, name)
+		}
+		fmt.Fprintf(bw, "define %s = [\n", name)
+		for i, r := range ranges {
+			separator := ","
+			if i == len(ranges)-1 {
+				separator = ""
+			}
+			if r.IsExact() {
+				fmt.Fprintf(bw, "    %s%s\n", r.Prefix, separator)
+			} else {
+				fmt.Fprintf(bw, "    %s{%d,%d}%s\n", r.Prefix, r.Min, r.Max, separator)
+			}
+		}
+		fmt.Fprint(bw, "];\n")
+	default:
+		return fmt.Errorf("unknown prefix list format: %d", format)
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,79 @@
+package rirs
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func prefixes(values ...string) []netip.Prefix {
+	result := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		result = append(result, netip.MustParsePrefix(value))
+	}
+	return result
+}
+
+func TestAggregate(t *testing.T) {
+	got := Aggregate(prefixes(
+		"192.0.2.0/25", "192.0.2.128/25",
+		"198.51.100.0/24", "198.51.100.0/25",
+		"203.0.113.0/25", "203.0.113.128/26",
+	))
+	want := []PrefixRange{
+		{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Min: 25, Max: 25},
+		{Prefix: netip.MustParsePrefix("198.51.100.0/24"), Min: 24, Max: 25},
+		{Prefix: netip.MustParsePrefix("203.0.113.0/25"), Min: 25, Max: 25},
+		{Prefix: netip.MustParsePrefix("203.0.113.128/26"), Min: 26, Max: 26},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Aggregate() = %v, want %v", got, want)
+	}
+}
+
+func TestWritePrefixList(t *testing.T) {
+	ranges := []PrefixRange{
+		{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Min: 25, Max: 25},
+		{Prefix: netip.MustParsePrefix("198.51.100.0/24"), Min: 24, Max: 25},
+		{Prefix: netip.MustParsePrefix("203.0.113.0/25"), Min: 25, Max: 25},
+	}
+	tests := []struct {
+		format PrefixListFormat
+		want   string
+	}{
+		{PrefixListPlain, "192.0.2.0/24^25-25\n198.51.100.0/24^24-25\n203.0.113.0/25\n"},
+		{PrefixListCisco, "no ip prefix-list X\nno ipv6 prefix-list X\n" +
+			"ip prefix-list X permit 192.0.2.0/24 ge 25 le 25\n" +
+			"ip prefix-list X permit 198.51.100.0/24 le 25\n" +
+			"ip prefix-list X permit 203.0.113.0/25\n"},
+		{PrefixListJuniper, "policy-options {\n    replace:\n    route-filter-list X {\n" +
+			"        192.0.2.0/24 prefix-length-range /25-/25;\n" +
+			"        198.51.100.0/24 upto /25;\n" +
+			"        203.0.113.0/25 exact;\n    }\n}\n"},
+		{PrefixListBird, "define X = [\n    192.0.2.0/24{25,25},\n    198.51.100.0/24{24,25},\n    203.0.113.0/25\n];\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WritePrefixList(&buf, tt.format, "X", ranges); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("format %d:\n%s\nwant\n%s", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	if err := WritePrefixList(&bytes.Buffer{}, PrefixListBird, "X", nil); !errors.Is(err, ErrEmptyPrefixList) {
+		t.Errorf("empty BIRD list: err = %v, want ErrEmptyPrefixList", err)
+	}
+
+	mixed := Exact(prefixes("192.0.2.0/24", "2001:db8::/32"))
+	if err := WritePrefixList(&bytes.Buffer{}, PrefixListBird, "X", mixed); !errors.Is(err, ErrMixedFamilies) {
+		t.Errorf("mixed BIRD list: err = %v, want ErrMixedFamilies", err)
+	}
+	v4, v6 := SplitFamilies(mixed)
+	if len(v4) != 1 || len(v6) != 1 || !v4[0].Prefix.Addr().Is4() || !v6[0].Prefix.Addr().Is6() {
+		t.Errorf("SplitFamilies() = %v, %v", v4, v6)
+	}
+}
@@ -0,0 +1,108 @@
+package rirs
+
+import "net/netip"
+
+// prefixTree is a path-compressed binary trie (Patricia tree) mapping prefixes
+// to values, with one root per address family
+type prefixTree[T any] struct {
+	v4 *treeNode[T]
+	v6 *treeNode[T]
+}
+
+type treeNode[T any] struct {
+	prefix   netip.Prefix
+	values   []T
+	children [2]*treeNode[T]
+}
+
+func (t *prefixTree[T]) root(addr netip.Addr) **treeNode[T] {
+	if addr.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+func (t *prefixTree[T]) insert(prefix netip.Prefix, value T) {
+	prefix = prefix.Masked()
+	node := t.root(prefix.Addr())
+
+	for {
+		n := *node
+		if n == nil {
+			*node = &treeNode[T]{prefix: prefix, values: []T{value}}
+			return
+		}
+
+		common := commonBits(n.prefix, prefix)
+		switch {
+		case common == n.prefix.Bits() && common == prefix.Bits():
+			n.values = append(n.values, value)
+			return
+		case common == n.prefix.Bits():
+			// n covers prefix, descend
+			node = &n.children[bitAt(prefix.Addr(), common)]
+			continue
+		case common == prefix.Bits():
+			// prefix covers n, put it in between
+			inserted := &treeNode[T]{prefix: prefix, values: []T{value}}
+			inserted.children[bitAt(n.prefix.Addr(), common)] = n
+			*node = inserted
+			return
+		default:
+			// the two diverge, join them under a node without values
+			glue := &treeNode[T]{prefix: netip.PrefixFrom(prefix.Addr(), common).Masked()}
+			glue.children[bitAt(n.prefix.Addr(), common)] = n
+			glue.children[bitAt(prefix.Addr(), common)] = &treeNode[T]{prefix: prefix, values: []T{value}}
+			*node = glue
+			return
+		}
+	}
+}
+
+// covering returns the nodes with values whose prefix covers prefix, least specific first
+func (t *prefixTree[T]) covering(prefix netip.Prefix) []*treeNode[T] {
+	prefix = prefix.Masked()
+	nodes := make([]*treeNode[T], 0)
+
+	n := *t.root(prefix.Addr())
+	for n != nil && n.prefix.Bits() <= prefix.Bits() && n.prefix.Contains(prefix.Addr()) {
+		if len(n.values) > 0 {
+			nodes = append(nodes, n)
+		}
+		if n.prefix.Bits() == prefix.Bits() {
+			break
+		}
+		n = n.children[bitAt(prefix.Addr(), n.prefix.Bits())]
+	}
+
+	return nodes
+}
+
+// commonBits returns the length of the common leading part of a and b
+func commonBits(a, b netip.Prefix) int {
+	limit := min(a.Bits(), b.Bits())
+	as, bs := a.Addr().AsSlice(), b.Addr().AsSlice()
+
+	bits := 0
+	for i := range as {
+		if bits >= limit {
+			break
+		}
+		diff := as[i] ^ bs[i]
+		if diff == 0 {
+			bits += 8
+			continue
+		}
+		for mask := byte(0x80); mask&diff == 0; mask >>= 1 {
+			bits++
+		}
+		break
+	}
+	return min(bits, limit)
+}
+
+// bitAt returns the bit of addr at position i, counting from the most significant bit
+func bitAt(addr netip.Addr, i int) int {
+	bytes := addr.AsSlice()
+	return int(bytes[i/8]>>(7-uint(i%8))) & 1
+}
@@ -0,0 +1,176 @@
+package rirs
+
+import (
+	"fmt"
+	"iter"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aredoff/rirs/fs"
+	"github.com/aredoff/rirs/parser"
+)
+
+// Database reads back the database folder written by Sync without re-parsing the dumps.
+//
+// Every iterator streams the objects of one class from the given sources, or from
+// all sources when none are given. Objects that fail to decode are yielded as an
+// error wrapping ErrMalformed and skipped; any other error ends the current source.
+type Database struct {
+	folder *fs.Folder
+}
+
+// Open opens the database of the folder that was passed to New
+func Open(folder *fs.Folder) (*Database, error) {
+	if !folder.Exist("database") {
+		return nil, fmt.Errorf("no database found in %s", folder.Path())
+	}
+	databaseFolder, err := folder.SubFolder("database")
+	if err != nil {
+		return nil, err
+	}
+	return &Database{folder: databaseFolder}, nil
+}
+
+// Sources returns the names of the synced sources in alphabetical order
+func (d *Database) Sources() ([]string, error) {
+	entries, err := os.ReadDir(d.folder.Path())
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			sources = append(sources, entry.Name())
+		}
+	}
+	sort.Strings(sources)
+	return sources, nil
+}
+
+// resolveSources returns sources, or all sources when none are given
+func (d *Database) resolveSources(sources []string) ([]string, error) {
+	if len(sources) > 0 {
+		return sources, nil
+	}
+	return d.Sources()
+}
+
+// Load reads one source into memory
+func (d *Database) Load(source string) (*parser.RipeDatabase, error) {
+	if !d.folder.Exist(source) {
+		return nil, fmt.Errorf("source %s not found", source)
+	}
+	folder, err := d.folder.SubFolder(source)
+	if err != nil {
+		return nil, err
+	}
+	return LoadDatabase(folder)
+}
+
+func (d *Database) ASNs(sources ...string) iter.Seq2[*parser.ASN, error] {
+	return typed[*parser.ASN](d.objects("aut-num", sources))
+}
+
+func (d *Database) InetNums(sources ...string) iter.Seq2[*parser.InetNum, error] {
+	return typed[*parser.InetNum](d.objects("inetnum", sources))
+}
+
+func (d *Database) Inet6Nums(sources ...string) iter.Seq2[*parser.Inet6Num, error] {
+	return typed[*parser.Inet6Num](d.objects("inet6num", sources))
+}
+
+func (d *Database) Routes(sources ...string) iter.Seq2[*parser.Route, error] {
+	return typed[*parser.Route](d.objects("route", sources))
+}
+
+func (d *Database) Routes6(sources ...string) iter.Seq2[*parser.Route6, error] {
+	return typed[*parser.Route6](d.objects("route6", sources))
+}
+
+func (d *Database) Persons(sources ...string) iter.Seq2[*parser.Person, error] {
+	return typed[*parser.Person](d.objects("person", sources))
+}
+
+func (d *Database) Roles(sources ...string) iter.Seq2[*parser.Role, error] {
+	return typed[*parser.Role](d.objects("role", sources))
+}
+
+func (d *Database) Mntners(sources ...string) iter.Seq2[*parser.Mntner, error] {
+	return typed[*parser.Mntner](d.objects("mntner", sources))
+}
+
+func (d *Database) Irts(sources ...string) iter.Seq2[*parser.Irt, error] {
+	return typed[*parser.Irt](d.objects("irt", sources))
+}
+
+func (d *Database) Organizations(sources ...string) iter.Seq2[*parser.Organization, error] {
+	return typed[*parser.Organization](d.objects("organisation", sources))
+}
+
+func (d *Database) Domains(sources ...string) iter.Seq2[*parser.Domain, error] {
+	return typed[*parser.Domain](d.objects("domain", sources))
+}
+
+func (d *Database) AsSets(sources ...string) iter.Seq2[*parser.AsSet, error] {
+	return typed[*parser.AsSet](d.objects("as-set", sources))
+}
+
+func (d *Database) RouteSets(sources ...string) iter.Seq2[*parser.RouteSet, error] {
+	return typed[*parser.RouteSet](d.objects("route-set", sources))
+}
+
+func (d *Database) FilterSets(sources ...string) iter.Seq2[*parser.FilterSet, error] {
+	return typed[*parser.FilterSet](d.objects("filter-set", sources))
+}
+
+func (d *Database) PeeringSets(sources ...string) iter.Seq2[*parser.PeeringSet, error] {
+	return typed[*parser.PeeringSet](d.objects("peering-set", sources))
+}
+
+func (d *Database) RtrSets(sources ...string) iter.Seq2[*parser.RtrSet, error] {
+	return typed[*parser.RtrSet](d.objects("rtr-set", sources))
+}
+
+func (d *Database) objects(class string, sources []string) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		// Resolved on every iteration, sources written after the first one are included
+		names, err := d.resolveSources(sources)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, source := range names {
+			if !d.folder.Exist(source) {
+				if !yield(nil, fmt.Errorf("source %s not found", source)) {
+					return
+				}
+				continue
+			}
+			folder, err := d.folder.SubFolder(source)
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			for stored, err := range readFolder(folder, class) {
+				if !yield(stored.obj, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func typed[T any](seq iter.Seq2[interface{}, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for obj, err := range seq {
+			typedObj, _ := obj.(T)
+			if !yield(typedObj, err) {
+				return
+			}
+		}
+	}
+}
@@ -1,8 +1,62 @@
 package rirs
 
-import "github.com/aredoff/rirs/fs"
+import (
+	"fmt"
+	"os"
+	"sync"
 
-func New(folder *fs.Folder) (*rir, error) {
+	"github.com/aredoff/rirs/fs"
+	"github.com/aredoff/rirs/parser"
+)
+
+// Format selects how Sync writes the database folder of each source
+type Format int
+
+const (
+	// FormatJSON writes one JSON object per type, such as asns.json and routes.json
+	FormatJSON Format = iota
+	// FormatJSONL writes a single objects.jsonl file with one object per line
+	FormatJSONL
+)
+
+// StorageFactory creates the storage a source is parsed into. Storages that
+// implement io.Closer are closed once all files of the source are parsed.
+// When the source fails, storages that implement Aborter are aborted instead.
+type StorageFactory func(source string) (parser.Storage, error)
+
+// Aborter is implemented by storages that can drop what was saved into them,
+// keeping the objects stored by the previous sync of the source
+type Aborter interface {
+	Abort() error
+}
+
+type Option func(*rir)
+
+func WithFormat(format Format) Option {
+	return func(r *rir) {
+		r.format = format
+	}
+}
+
+// WithStorageFactory replaces the built-in file storages, so sources can be
+// written straight into another backend. The format option is ignored then.
+func WithStorageFactory(factory StorageFactory) Option {
+	return func(r *rir) {
+		r.storageFactory = factory
+	}
+}
+
+// WithConcurrency sets how many files are downloaded and parsed at the same time,
+// across and within sources. The default is 1. Above that, storages from
+// WithStorageFactory must be safe for concurrent Save calls. The built-in JSON
+// storage numbers repeated keys the same whichever file of a source finishes first.
+func WithConcurrency(n int) Option {
+	return func(r *rir) {
+		r.concurrency = max(n, 1)
+	}
+}
+
+func New(folder *fs.Folder, opts ...Option) (*rir, error) {
 	downloadFolder, err := folder.SubFolder("download")
 	if err != nil {
 		return nil, err
@@ -16,15 +70,87 @@ func New(folder *fs.Folder) (*rir, error) {
 		return nil, err
 	}
 
-	return &rir{
+	r := &rir{
 		downloadFolder: downloadFolder,
 		extractFolder:  extractFolder,
 		databaseFolder: databaseFolder,
-	}, nil
+		concurrency:    1,
+		sets:           NewSetIndex(),
+		sourceSets:     make(map[string]*SetIndex),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
 }
 
 type rir struct {
 	downloadFolder *fs.Folder
 	extractFolder  *fs.Folder
 	databaseFolder *fs.Folder
+	format         Format
+	storageFactory StorageFactory
+	concurrency    int
+	// sets is the union of sourceSets. The index of a source is only replaced
+	// once the source synced, so a failed source keeps its previous one.
+	sets       *SetIndex
+	setsMu     sync.Mutex
+	sourceSets map[string]*SetIndex
+}
+
+// newStorage creates the storage source is parsed into. The built-in storages
+// write into the staging folder of source, which publish moves into place.
+func (r *rir) newStorage(source string) (parser.Storage, error) {
+	if r.storageFactory != nil {
+		return r.storageFactory(source)
+	}
+
+	// Start from an empty folder, an interrupted sync may have left one behind
+	if err := r.discard(source); err != nil {
+		return nil, err
+	}
+	folder, err := r.databaseFolder.SubFolder(stagingName(source))
+	if err != nil {
+		return nil, err
+	}
+	if r.format == FormatJSONL {
+		return NewJSONLStorage(folder, source)
+	}
+	return NewStorage(folder)
+}
+
+// stagingName is the hidden folder the built-in storages of source write to until
+// the source is complete. Database.Sources skips dot-prefixed folders.
+func stagingName(source string) string {
+	return "." + source + ".tmp"
+}
+
+// publish replaces the database folder of source with its staging folder
+func (r *rir) publish(source string) error {
+	if r.storageFactory != nil {
+		return nil
+	}
+
+	previous := r.databaseFolder.GetPath("." + source + ".old")
+	if err := os.RemoveAll(previous); err != nil {
+		return err
+	}
+	if r.databaseFolder.Exist(source) {
+		if err := os.Rename(r.databaseFolder.GetPath(source), previous); err != nil {
+			return fmt.Errorf("failed to move database of %s aside: %w", source, err)
+		}
+	}
+	if err := os.Rename(r.databaseFolder.GetPath(stagingName(source)), r.databaseFolder.GetPath(source)); err != nil {
+		return fmt.Errorf("failed to publish database of %s: %w", source, err)
+	}
+	return os.RemoveAll(previous)
+}
+
+// discard removes the staging folder of source
+func (r *rir) discard(source string) error {
+	if r.storageFactory != nil {
+		return nil
+	}
+	return os.RemoveAll(r.databaseFolder.GetPath(stagingName(source)))
 }
@@ -0,0 +1,112 @@
+package rirs
+
+import (
+	"errors"
+	"net/netip"
+	"sync"
+
+	"github.com/aredoff/rirs/parser"
+)
+
+// RouteOrigin is a route or route6 object covering a looked up address or prefix
+type RouteOrigin struct {
+	Source string
+	Prefix netip.Prefix
+	Origin string
+	// Route is set for IPv4 objects and Route6 for IPv6 ones
+	Route  *parser.Route
+	Route6 *parser.Route6
+}
+
+// RouteIndex finds the IRR route objects covering an address or prefix.
+// The same prefix is often registered with several origins and in several
+// sources, so every matching object is returned.
+type RouteIndex struct {
+	mu   sync.RWMutex
+	tree prefixTree[*RouteOrigin]
+}
+
+func NewRouteIndex() *RouteIndex {
+	return &RouteIndex{}
+}
+
+// RouteIndex builds a RouteIndex from the route and route6 objects of the given
+// sources, or of all sources. Malformed entries are skipped.
+func (d *Database) RouteIndex(sources ...string) (*RouteIndex, error) {
+	sources, err := d.resolveSources(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := NewRouteIndex()
+	for _, source := range sources {
+		for route, err := range d.Routes(source) {
+			if errors.Is(err, ErrMalformed) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			_ = idx.AddRoute(source, route)
+		}
+		for route6, err := range d.Routes6(source) {
+			if errors.Is(err, ErrMalformed) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			_ = idx.AddRoute6(source, route6)
+		}
+	}
+	return idx, nil
+}
+
+func (idx *RouteIndex) AddRoute(source string, route *parser.Route) error {
+	prefix, err := parsePrefix(route.Prefix)
+	if err != nil {
+		return err
+	}
+	idx.add(&RouteOrigin{Source: source, Prefix: prefix, Origin: route.Origin, Route: route})
+	return nil
+}
+
+func (idx *RouteIndex) AddRoute6(source string, route6 *parser.Route6) error {
+	prefix, err := parsePrefix(route6.Prefix)
+	if err != nil {
+		return err
+	}
+	idx.add(&RouteOrigin{Source: source, Prefix: prefix, Origin: route6.Origin, Route6: route6})
+	return nil
+}
+
+func (idx *RouteIndex) add(origin *RouteOrigin) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.tree.insert(origin.Prefix, origin)
+}
+
+// LookupIP returns the route objects covering addr, the most specific first
+func (idx *RouteIndex) LookupIP(addr netip.Addr) []*RouteOrigin {
+	addr = addr.Unmap()
+	return idx.LookupPrefix(netip.PrefixFrom(addr, addr.BitLen()))
+}
+
+// LookupPrefix returns the route objects whose prefix equals or covers prefix,
+// the most specific first. It returns nil when nothing matches.
+func (idx *RouteIndex) LookupPrefix(prefix netip.Prefix) []*RouteOrigin {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	nodes := idx.tree.covering(prefix)
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	origins := make([]*RouteOrigin, 0, len(nodes))
+	for i := len(nodes) - 1; i >= 0; i-- {
+		origins = append(origins, nodes[i].values...)
+	}
+	return origins
+}
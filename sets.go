@@ -0,0 +1,497 @@
+package rirs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aredoff/rirs/fs"
+	"github.com/aredoff/rirs/parser"
+)
+
+const (
+	// DefaultExpandDepth limits how deep nested sets are followed
+	DefaultExpandDepth = 16
+)
+
+var (
+	ErrSetNotFound = errors.New("set not found")
+	ErrExpandDepth = errors.New("set expansion depth limit exceeded")
+)
+
+// SetIndex keeps the objects needed to expand as-sets and route-sets. A rir keeps
+// one for every synced source; Database.SetIndex builds one from a previous sync.
+type SetIndex struct {
+	mu        sync.RWMutex
+	asSets    map[string][]*parser.AsSet
+	routeSets map[string][]*parser.RouteSet
+	// members declared from the member side with member-of, keyed by set name
+	autNumMembers map[string][]setMember
+	routeMembers  map[string][]setMember
+	// route and route6 prefixes keyed by origin ASN
+	routesByOrigin map[string][]netip.Prefix
+}
+
+type setMember struct {
+	value string
+	mntBy []string
+}
+
+func NewSetIndex() *SetIndex {
+	idx := &SetIndex{}
+	idx.reset()
+	return idx
+}
+
+func (idx *SetIndex) reset() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.asSets = make(map[string][]*parser.AsSet)
+	idx.routeSets = make(map[string][]*parser.RouteSet)
+	idx.autNumMembers = make(map[string][]setMember)
+	idx.routeMembers = make(map[string][]setMember)
+	idx.routesByOrigin = make(map[string][]netip.Prefix)
+}
+
+// replace makes idx the union of indexes, in their order
+func (idx *SetIndex) replace(indexes []*SetIndex) {
+	merged := NewSetIndex()
+	for _, other := range indexes {
+		other.mu.RLock()
+		appendAll(merged.asSets, other.asSets)
+		appendAll(merged.routeSets, other.routeSets)
+		appendAll(merged.autNumMembers, other.autNumMembers)
+		appendAll(merged.routeMembers, other.routeMembers)
+		appendAll(merged.routesByOrigin, other.routesByOrigin)
+		other.mu.RUnlock()
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.asSets = merged.asSets
+	idx.routeSets = merged.routeSets
+	idx.autNumMembers = merged.autNumMembers
+	idx.routeMembers = merged.routeMembers
+	idx.routesByOrigin = merged.routesByOrigin
+}
+
+func appendAll[T any](dst, src map[string][]T) {
+	for key, values := range src {
+		dst[key] = append(dst[key], values...)
+	}
+}
+
+func (idx *SetIndex) addAsSet(asSet *parser.AsSet) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	name := strings.ToUpper(asSet.Name)
+	idx.asSets[name] = append(idx.asSets[name], asSet)
+}
+
+func (idx *SetIndex) addRouteSet(routeSet *parser.RouteSet) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	name := strings.ToUpper(routeSet.Name)
+	idx.routeSets[name] = append(idx.routeSets[name], routeSet)
+}
+
+func (idx *SetIndex) addAutNumMember(asn string, memberOf, mntBy []string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, name := range memberOf {
+		name = strings.ToUpper(name)
+		idx.autNumMembers[name] = append(idx.autNumMembers[name], setMember{value: strings.ToUpper(asn), mntBy: mntBy})
+	}
+}
+
+func (idx *SetIndex) addRouteMember(prefix string, memberOf, mntBy []string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, name := range memberOf {
+		name = strings.ToUpper(name)
+		idx.routeMembers[name] = append(idx.routeMembers[name], setMember{value: prefix, mntBy: mntBy})
+	}
+}
+
+func (idx *SetIndex) addRouteOrigin(prefix, origin string) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil || !isASN(origin) {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	origin = strings.ToUpper(origin)
+	idx.routesByOrigin[origin] = append(idx.routesByOrigin[origin], p.Masked())
+}
+
+// addObject records obj if it is one of the classes the index keeps
+func (idx *SetIndex) addObject(obj interface{}) {
+	switch obj := obj.(type) {
+	case *parser.ASN:
+		if len(obj.MemberOf) > 0 {
+			idx.addAutNumMember(obj.ASNumber, obj.MemberOf, obj.MntBy)
+		}
+	case *parser.Route:
+		idx.addRouteOrigin(obj.Prefix, obj.Origin)
+		if len(obj.MemberOf) > 0 {
+			idx.addRouteMember(obj.Prefix, obj.MemberOf, obj.MntBy)
+		}
+	case *parser.Route6:
+		idx.addRouteOrigin(obj.Prefix, obj.Origin)
+		if len(obj.MemberOf) > 0 {
+			idx.addRouteMember(obj.Prefix, obj.MemberOf, obj.MntBy)
+		}
+	case *parser.AsSet:
+		idx.addAsSet(obj)
+	case *parser.RouteSet:
+		idx.addRouteSet(obj)
+	}
+}
+
+// addFolder records the objects of a database folder written by a previous sync
+func (idx *SetIndex) addFolder(folder *fs.Folder) error {
+	for stored, err := range readFolder(folder, "aut-num", "route", "route6", "as-set", "route-set") {
+		if errors.Is(err, ErrMalformed) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		idx.addObject(stored.obj)
+	}
+	return nil
+}
+
+// indexStorage forwards every object to the wrapped storage and records
+// the ones the set index needs on the way
+type indexStorage struct {
+	parser.Storage
+	sets *SetIndex
+}
+
+func (s *indexStorage) SaveASN(asn *parser.ASN) error {
+	s.sets.addObject(asn)
+	return s.Storage.SaveASN(asn)
+}
+
+func (s *indexStorage) SaveRoute(route *parser.Route) error {
+	s.sets.addObject(route)
+	return s.Storage.SaveRoute(route)
+}
+
+func (s *indexStorage) SaveRoute6(route6 *parser.Route6) error {
+	s.sets.addObject(route6)
+	return s.Storage.SaveRoute6(route6)
+}
+
+func (s *indexStorage) SaveAsSet(asSet *parser.AsSet) error {
+	s.sets.addObject(asSet)
+	return s.Storage.SaveAsSet(asSet)
+}
+
+func (s *indexStorage) SaveRouteSet(routeSet *parser.RouteSet) error {
+	s.sets.addObject(routeSet)
+	return s.Storage.SaveRouteSet(routeSet)
+}
+
+// discardStorage drops every object, it is wrapped in an indexStorage when only
+// the set index of a source is needed
+type discardStorage struct{}
+
+func (discardStorage) SaveASN(*parser.ASN) error                   { return nil }
+func (discardStorage) SaveInetNum(*parser.InetNum) error           { return nil }
+func (discardStorage) SaveInet6Num(*parser.Inet6Num) error         { return nil }
+func (discardStorage) SaveRoute(*parser.Route) error               { return nil }
+func (discardStorage) SaveRoute6(*parser.Route6) error             { return nil }
+func (discardStorage) SavePerson(*parser.Person) error             { return nil }
+func (discardStorage) SaveRole(*parser.Role) error                 { return nil }
+func (discardStorage) SaveMntner(*parser.Mntner) error             { return nil }
+func (discardStorage) SaveIrt(*parser.Irt) error                   { return nil }
+func (discardStorage) SaveOrganization(*parser.Organization) error { return nil }
+func (discardStorage) SaveDomain(*parser.Domain) error             { return nil }
+func (discardStorage) SaveAsSet(*parser.AsSet) error               { return nil }
+func (discardStorage) SaveRouteSet(*parser.RouteSet) error         { return nil }
+func (discardStorage) SaveFilterSet(*parser.FilterSet) error       { return nil }
+func (discardStorage) SavePeeringSet(*parser.PeeringSet) error     { return nil }
+func (discardStorage) SaveRtrSet(*parser.RtrSet) error             { return nil }
+
+// SetIndex builds a SetIndex from the aut-num, route, route6, as-set and route-set
+// objects of the given sources, or of all sources. Malformed entries are skipped.
+func (d *Database) SetIndex(sources ...string) (*SetIndex, error) {
+	sources, err := d.resolveSources(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := NewSetIndex()
+	for _, source := range sources {
+		if !d.folder.Exist(source) {
+			return nil, fmt.Errorf("source %s not found", source)
+		}
+		folder, err := d.folder.SubFolder(source)
+		if err != nil {
+			return nil, err
+		}
+		if err := idx.addFolder(folder); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// Expand expands an as-set of all synced sources, see SetIndex.Expand
+func (r *rir) Expand(ctx context.Context, name string) ([]string, error) {
+	return r.sets.Expand(ctx, name)
+}
+
+// ExpandDepth is Expand with an explicit nesting limit
+func (r *rir) ExpandDepth(ctx context.Context, name string, maxDepth int) ([]string, error) {
+	return r.sets.ExpandDepth(ctx, name, maxDepth)
+}
+
+// ExpandRouteSet expands a route-set of all synced sources, see SetIndex.ExpandRouteSet
+func (r *rir) ExpandRouteSet(ctx context.Context, name string) ([]string, error) {
+	return r.sets.ExpandRouteSet(ctx, name)
+}
+
+// Expand flattens an as-set into the sorted list of ASNs it contains,
+// following nested as-sets up to DefaultExpandDepth levels.
+// A plain ASN expands to itself. Sets that reference each other are expanded once.
+func (idx *SetIndex) Expand(ctx context.Context, name string) ([]string, error) {
+	return idx.ExpandDepth(ctx, name, DefaultExpandDepth)
+}
+
+// ExpandDepth is Expand with an explicit nesting limit
+func (idx *SetIndex) ExpandDepth(ctx context.Context, name string, maxDepth int) ([]string, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	e := newExpansion(ctx, idx, maxDepth)
+	name = strings.ToUpper(name)
+	if isASN(name) {
+		return []string{name}, nil
+	}
+	if _, ok := idx.asSets[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSetNotFound, name)
+	}
+	if err := e.asSet(name, 0); err != nil {
+		return nil, err
+	}
+	return e.asns(), nil
+}
+
+// ExpandRouteSet flattens a route-set into the sorted list of prefixes it contains,
+// following nested route-sets up to DefaultExpandDepth levels. ASN and as-set members
+// contribute the prefixes of the route objects they originate. Range operators
+// such as ^+ are kept on the returned prefixes.
+func (idx *SetIndex) ExpandRouteSet(ctx context.Context, name string) ([]string, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	e := newExpansion(ctx, idx, DefaultExpandDepth)
+	name = strings.ToUpper(name)
+	if _, ok := idx.routeSets[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSetNotFound, name)
+	}
+	if err := e.routeSet(name, "", 0); err != nil {
+		return nil, err
+	}
+	return e.prefixes(), nil
+}
+
+type expansion struct {
+	ctx      context.Context
+	index    *SetIndex
+	maxDepth int
+	seen     map[string]bool
+	result   map[string]struct{}
+}
+
+func newExpansion(ctx context.Context, index *SetIndex, maxDepth int) *expansion {
+	return &expansion{
+		ctx:      ctx,
+		index:    index,
+		maxDepth: maxDepth,
+		seen:     make(map[string]bool),
+		result:   make(map[string]struct{}),
+	}
+}
+
+func (e *expansion) asSet(name string, depth int) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	// Already expanded, either through another branch or through a cycle
+	if e.seen[name] {
+		return nil
+	}
+	if depth > e.maxDepth {
+		return fmt.Errorf("%w: %s", ErrExpandDepth, name)
+	}
+	e.seen[name] = true
+
+	for _, set := range e.index.asSets[name] {
+		for _, member := range set.Members {
+			member = strings.ToUpper(member)
+			if isASN(member) {
+				e.result[member] = struct{}{}
+				continue
+			}
+			if err := e.asSet(member, depth+1); err != nil {
+				return err
+			}
+		}
+		for _, member := range e.index.autNumMembers[name] {
+			if referenceAllowed(set.MbrsByRef, member.mntBy) {
+				e.result[member.value] = struct{}{}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *expansion) routeSet(name, operator string, depth int) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	key := name + operator
+	if e.seen[key] {
+		return nil
+	}
+	if depth > e.maxDepth {
+		return fmt.Errorf("%w: %s", ErrExpandDepth, name)
+	}
+	e.seen[key] = true
+
+	for _, set := range e.index.routeSets[name] {
+		members := append(append([]string{}, set.Members...), set.MpMembers...)
+		for _, member := range members {
+			member, memberOperator, _ := strings.Cut(member, "^")
+			if memberOperator != "" {
+				memberOperator = "^" + memberOperator
+			} else {
+				memberOperator = operator
+			}
+
+			if prefix, err := netip.ParsePrefix(member); err == nil {
+				e.result[prefix.String()+memberOperator] = struct{}{}
+				continue
+			}
+			member = strings.ToUpper(member)
+			switch {
+			case isASN(member):
+				e.addOriginated(member, memberOperator)
+			case strings.Contains(member, "RS-"):
+				if err := e.routeSet(member, memberOperator, depth+1); err != nil {
+					return err
+				}
+			case strings.Contains(member, "AS-"):
+				asns := newExpansion(e.ctx, e.index, e.maxDepth-depth)
+				if err := asns.asSet(member, 0); err != nil {
+					return err
+				}
+				for asn := range asns.result {
+					e.addOriginated(asn, memberOperator)
+				}
+			}
+		}
+		for _, member := range e.index.routeMembers[name] {
+			if referenceAllowed(set.MbrsByRef, member.mntBy) {
+				e.result[member.value+operator] = struct{}{}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *expansion) addOriginated(asn, operator string) {
+	for _, prefix := range e.index.routesByOrigin[asn] {
+		e.result[prefix.String()+operator] = struct{}{}
+	}
+}
+
+func (e *expansion) asns() []string {
+	asns := make([]string, 0, len(e.result))
+	for asn := range e.result {
+		asns = append(asns, asn)
+	}
+	sort.Slice(asns, func(i, j int) bool {
+		return asnNumber(asns[i]) < asnNumber(asns[j])
+	})
+	return asns
+}
+
+func (e *expansion) prefixes() []string {
+	prefixes := make([]string, 0, len(e.result))
+	for prefix := range e.result {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return comparePrefixes(prefixes[i], prefixes[j]) < 0
+	})
+	return prefixes
+}
+
+// referenceAllowed reports whether an object maintained by mntBy may join
+// a set through member-of, according to the set's mbrs-by-ref
+func referenceAllowed(mbrsByRef, mntBy []string) bool {
+	for _, ref := range mbrsByRef {
+		if strings.EqualFold(ref, "ANY") {
+			return true
+		}
+		for _, mnt := range mntBy {
+			if strings.EqualFold(ref, mnt) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isASN(value string) bool {
+	if len(value) < 3 || !strings.EqualFold(value[:2], "AS") {
+		return false
+	}
+	_, err := strconv.ParseUint(value[2:], 10, 32)
+	return err == nil
+}
+
+func asnNumber(asn string) uint64 {
+	n, _ := strconv.ParseUint(asn[2:], 10, 32)
+	return n
+}
+
+// comparePrefixes orders prefixes by address family, address and length,
+// ignoring range operators. Unparsable values sort last.
+func comparePrefixes(a, b string) int {
+	pa, errA := netip.ParsePrefix(strings.SplitN(a, "^", 2)[0])
+	pb, errB := netip.ParsePrefix(strings.SplitN(b, "^", 2)[0])
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	}
+	if c := pa.Addr().Compare(pb.Addr()); c != 0 {
+		return c
+	}
+	if c := pa.Bits() - pb.Bits(); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
@@ -8,6 +8,18 @@ type source struct {
 	Name          string
 	httpDatabases []string
 	ftpDatabases  []string
+	// checksums maps database URLs to the URL of their published checksum file.
+	// Databases without one are not verified.
+	checksums map[string]string
+}
+
+// md5Checksums maps every URL to the .md5 file published next to it
+func md5Checksums(urls []string) map[string]string {
+	checksums := make(map[string]string, len(urls))
+	for _, url := range urls {
+		checksums[url] = url + ".md5"
+	}
+	return checksums
 }
 
 func init() {
@@ -35,28 +47,31 @@ func init() {
 		httpDatabases: []string{"https://ftp.ripe.net/ripe/dbase/ripe.db.gz"},
 	})
 
+	apnicDatabases := []string{
+		"https://ftp.apnic.net/apnic/whois/apnic.db.as-block.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.as-set.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.aut-num.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.domain.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.filter-set.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.inet-rtr.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.inet6num.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.inetnum.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.irt.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.key-cert.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.limerick.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.mntner.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.organisation.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.peering-set.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.role.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.route-set.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.route.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.route6.gz",
+		"https://ftp.apnic.net/apnic/whois/apnic.db.rtr-set.gz",
+	}
+
 	sources = append(sources, source{
-		Name: "apnic",
-		httpDatabases: []string{
-			"https://ftp.apnic.net/apnic/whois/apnic.db.as-block.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.as-set.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.aut-num.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.domain.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.filter-set.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.inet-rtr.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.inet6num.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.inetnum.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.irt.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.key-cert.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.limerick.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.mntner.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.organisation.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.peering-set.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.role.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.route-set.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.route.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.route6.gz",
-			"https://ftp.apnic.net/apnic/whois/apnic.db.rtr-set.gz",
-		},
+		Name:          "apnic",
+		httpDatabases: apnicDatabases,
+		checksums:     md5Checksums(apnicDatabases),
 	})
 }
@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// table describes one object class table. Every table also has the id, source,
+// key, created and last_modified columns.
+type table struct {
+	name    string
+	columns []string
+	indexes []string
+}
+
+var tables = []table{
+	{name: "asns", columns: []string{"as_number", "as_name", "org", "abuse_c", "status"}, indexes: []string{"as_number", "org"}},
+	{name: "inetnums", columns: []string{"ip_range", "net_name", "country", "status", "org", "abuse_c"}, indexes: []string{"org", "country", "net_name"}},
+	{name: "inet6nums", columns: []string{"prefix", "net_name", "country", "status", "org", "abuse_c"}, indexes: []string{"org", "country", "net_name"}},
+	{name: "routes", columns: []string{"prefix", "origin", "org"}, indexes: []string{"prefix", "origin", "org"}},
+	{name: "routes6", columns: []string{"prefix", "origin", "org"}, indexes: []string{"prefix", "origin", "org"}},
+	{name: "persons", columns: []string{"name", "nic_hdl"}},
+	{name: "roles", columns: []string{"name", "abuse_mailbox", "nic_hdl"}},
+	{name: "mntners", columns: []string{"mntner", "abuse_mailbox"}},
+	{name: "irts", columns: []string{"irt", "abuse_mailbox"}},
+	{name: "organizations", columns: []string{"org_id", "name", "type", "abuse_c"}, indexes: []string{"name"}},
+	{name: "domains", columns: []string{"domain"}},
+	{name: "as_sets", columns: []string{"name"}},
+	{name: "route_sets", columns: []string{"name"}},
+	{name: "filter_sets", columns: []string{"name", "filter", "mp_filter"}},
+	{name: "peering_sets", columns: []string{"name"}},
+	{name: "rtr_sets", columns: []string{"name"}},
+}
+
+var tableByName = make(map[string]table)
+
+func init() {
+	for _, t := range tables {
+		tableByName[t.name] = t
+	}
+}
+
+// childTables hold multi-valued attributes, one row per value. Rows point to
+// their object through object_table and object_id and keep the value order in position.
+var childTables = []string{
+	"mnt_by", "admin_c", "tech_c", "descr", "address", "phone", "e_mail", "notify",
+	"member_of", "members", "mp_members", "mbrs_by_ref", "nserver", "zone_c",
+	"upd_to", "mnt_nfy", "auth", "irt_nfy", "peering", "mp_peering", "mnt_irt",
+}
+
+// migrate creates the schema, adding the columns that tables created before a
+// column was added to the package lack, such as abuse_c
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range tables {
+		existing, err := tableColumns(tx, t.name)
+		if err != nil {
+			return err
+		}
+		// A missing table is created by schema() below
+		if len(existing) == 0 {
+			continue
+		}
+		for _, column := range t.columns {
+			if existing[column] {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", t.name, column)); err != nil {
+				return fmt.Errorf("failed to add column %s to %s: %w", column, t.name, err)
+			}
+		}
+	}
+
+	for _, statement := range schema() {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// tableColumns returns the column names of a table, none when it does not exist
+func tableColumns(tx *sql.Tx, name string) (map[string]bool, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", name, err)
+		}
+		columns[column] = true
+	}
+	return columns, rows.Err()
+}
+
+func schema() []string {
+	statements := make([]string, 0)
+
+	for _, t := range tables {
+		columns := make([]string, 0, len(t.columns))
+		for _, column := range t.columns {
+			columns = append(columns, column+" TEXT")
+		}
+		statements = append(statements,
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+				id INTEGER PRIMARY KEY,
+				source TEXT NOT NULL,
+				key TEXT NOT NULL,
+				created TEXT,
+				last_modified TEXT,
+				%s
+			)`, t.name, strings.Join(columns, ",\n")),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_key ON %[1]s (key)", t.name),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_source ON %[1]s (source)", t.name),
+		)
+		for _, column := range t.indexes {
+			statements = append(statements,
+				fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_%[2]s ON %[1]s (%[2]s)", t.name, column))
+		}
+	}
+
+	for _, name := range childTables {
+		statements = append(statements,
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+				source TEXT NOT NULL,
+				object_table TEXT NOT NULL,
+				object_id INTEGER NOT NULL,
+				position INTEGER NOT NULL,
+				value TEXT NOT NULL
+			)`, name),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_object ON %[1]s (object_table, object_id)", name),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_value ON %[1]s (value)", name),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_source ON %[1]s (source)", name),
+		)
+	}
+
+	return statements
+}
+
+func insertStatement(t table) string {
+	columns := append([]string{"source", "key", "created", "last_modified"}, t.columns...)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", t.name, strings.Join(columns, ", "), placeholders)
+}
+
+func childInsertStatement(name string) string {
+	return fmt.Sprintf("INSERT INTO %s (source, object_table, object_id, position, value) VALUES (?, ?, ?, ?, ?)", name)
+}
@@ -0,0 +1,192 @@
+// Package sqlite stores parsed RPSL objects in a SQLite database, with one table
+// per object class and child tables for multi-valued attributes.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// batchSize is the number of objects written per transaction
+	batchSize = 50000
+)
+
+// DB is a SQLite database holding the objects of any number of sources.
+// Writes of all storages are serialized and committed in batches.
+type DB struct {
+	db      *sql.DB
+	mu      sync.Mutex
+	tx      *sql.Tx
+	stmts   map[string]*sql.Stmt
+	pending int
+}
+
+// Open opens the database at path, creating it and its schema if needed.
+// Tables missing columns added since they were created get them added.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+
+	return &DB{
+		db:    db,
+		stmts: make(map[string]*sql.Stmt),
+	}, nil
+}
+
+// SQL returns the underlying database for ad-hoc queries
+func (d *DB) SQL() *sql.DB {
+	return d.db
+}
+
+// Storage returns a parser.Storage writing the objects of one source.
+// Rows are written under a staging name and only replace the rows stored earlier
+// for the same source when the storage is closed, see Storage.Close and Storage.Abort.
+func (d *DB) Storage(source string) (*Storage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Rows left behind by a storage that was never closed or aborted
+	staging := stagingSource(source)
+	if err := d.transaction(func(tx *sql.Tx) error {
+		return deleteSource(tx, staging)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to clear staging rows of %s: %w", source, err)
+	}
+
+	return &Storage{
+		db:      d,
+		source:  source,
+		staging: staging,
+	}, nil
+}
+
+// Close commits pending writes and closes the database. Rows of storages that
+// were not closed yet stay under their staging name and are removed by the next
+// Storage call for the same source.
+func (d *DB) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.commit(); err != nil {
+		d.db.Close()
+		return err
+	}
+	return d.db.Close()
+}
+
+func (d *DB) begin() (*sql.Tx, error) {
+	if d.tx != nil {
+		return d.tx, nil
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	d.tx = tx
+	return tx, nil
+}
+
+func (d *DB) commit() error {
+	if d.tx == nil {
+		return nil
+	}
+	err := d.tx.Commit()
+	d.tx = nil
+	d.stmts = make(map[string]*sql.Stmt)
+	d.pending = 0
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// transaction commits the pending batch and runs fn in a transaction of its own,
+// rolled back when fn fails. The caller holds d.mu.
+func (d *DB) transaction(fn func(tx *sql.Tx) error) error {
+	if err := d.commit(); err != nil {
+		return err
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// stagingSource is the source name the rows of source are written under until
+// its storage is closed. Like the staging folders of the file storages it starts
+// with a dot, which RIR source names do not.
+func stagingSource(source string) string {
+	return "." + source + ".tmp"
+}
+
+// allTables returns the names of the object and child tables
+func allTables() []string {
+	names := make([]string, 0, len(tables)+len(childTables))
+	for _, t := range tables {
+		names = append(names, t.name)
+	}
+	return append(names, childTables...)
+}
+
+func deleteSource(tx *sql.Tx, source string) error {
+	for _, name := range allTables() {
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE source = ?", name), source); err != nil {
+			return fmt.Errorf("failed to clear %s for %s: %w", name, source, err)
+		}
+	}
+	return nil
+}
+
+func renameSource(tx *sql.Tx, from, to string) error {
+	for _, name := range allTables() {
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET source = ? WHERE source = ?", name), to, from); err != nil {
+			return fmt.Errorf("failed to move %s rows of %s: %w", name, to, err)
+		}
+	}
+	return nil
+}
+
+// stmt returns the insert statement prepared for the current transaction
+func (d *DB) stmt(name, query string) (*sql.Stmt, error) {
+	if stmt, ok := d.stmts[name]; ok {
+		return stmt, nil
+	}
+	tx, err := d.begin()
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert into %s: %w", name, err)
+	}
+	d.stmts[name] = stmt
+	return stmt, nil
+}
+
+func timeValue(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
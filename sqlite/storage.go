@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aredoff/rirs/parser"
+)
+
+// Storage writes the objects of one source into a DB
+type Storage struct {
+	db      *DB
+	source  string
+	staging string
+}
+
+// children maps child table names to the values stored in them
+type children map[string][]string
+
+func (s *Storage) SaveASN(asn *parser.ASN) error {
+	return s.insert("asns", &asn.BaseObject,
+		[]interface{}{asn.ASNumber, asn.ASName, asn.Org, asn.AbuseC, asn.Status},
+		children{"descr": asn.Description, "notify": asn.Notify, "member_of": asn.MemberOf})
+}
+
+func (s *Storage) SaveInetNum(inetnum *parser.InetNum) error {
+	return s.insert("inetnums", &inetnum.BaseObject,
+		[]interface{}{inetnum.IPRange, inetnum.NetName, inetnum.Country, inetnum.Status, inetnum.Org, inetnum.AbuseC},
+		children{"descr": inetnum.Description, "mnt_irt": inetnum.MntIrt})
+}
+
+func (s *Storage) SaveInet6Num(inet6num *parser.Inet6Num) error {
+	return s.insert("inet6nums", &inet6num.BaseObject,
+		[]interface{}{inet6num.Prefix, inet6num.NetName, inet6num.Country, inet6num.Status, inet6num.Org, inet6num.AbuseC},
+		children{"descr": inet6num.Description, "mnt_irt": inet6num.MntIrt})
+}
+
+func (s *Storage) SaveRoute(route *parser.Route) error {
+	return s.insert("routes", &route.BaseObject,
+		[]interface{}{route.Prefix, route.Origin, route.Org},
+		children{"descr": route.Description, "member_of": route.MemberOf})
+}
+
+func (s *Storage) SaveRoute6(route6 *parser.Route6) error {
+	return s.insert("routes6", &route6.BaseObject,
+		[]interface{}{route6.Prefix, route6.Origin, route6.Org},
+		children{"descr": route6.Description, "member_of": route6.MemberOf})
+}
+
+func (s *Storage) SavePerson(person *parser.Person) error {
+	return s.insert("persons", &person.BaseObject,
+		[]interface{}{person.Name, person.NicHdl},
+		children{"address": person.Address, "phone": person.Phone, "e_mail": person.Email})
+}
+
+func (s *Storage) SaveRole(role *parser.Role) error {
+	return s.insert("roles", &role.BaseObject,
+		[]interface{}{role.Name, role.AbuseMailbox, role.NicHdl},
+		children{"address": role.Address, "phone": role.Phone, "e_mail": role.Email})
+}
+
+func (s *Storage) SaveMntner(mntner *parser.Mntner) error {
+	return s.insert("mntners", &mntner.BaseObject,
+		[]interface{}{mntner.Mntner, mntner.AbuseMailbox},
+		children{"descr": mntner.Description, "upd_to": mntner.UpdTo, "mnt_nfy": mntner.MntNfy, "auth": mntner.Auth})
+}
+
+func (s *Storage) SaveIrt(irt *parser.Irt) error {
+	return s.insert("irts", &irt.BaseObject,
+		[]interface{}{irt.Irt, irt.AbuseMailbox},
+		children{"address": irt.Address, "phone": irt.Phone, "e_mail": irt.Email, "irt_nfy": irt.IrtNfy, "auth": irt.Auth})
+}
+
+func (s *Storage) SaveOrganization(org *parser.Organization) error {
+	return s.insert("organizations", &org.BaseObject,
+		[]interface{}{org.OrgID, org.Name, org.Type, org.AbuseC},
+		children{"address": org.Address, "e_mail": org.Email})
+}
+
+func (s *Storage) SaveDomain(domain *parser.Domain) error {
+	return s.insert("domains", &domain.BaseObject,
+		[]interface{}{domain.Domain},
+		children{"descr": domain.Description, "nserver": domain.Nameservers, "zone_c": domain.ZoneC})
+}
+
+func (s *Storage) SaveAsSet(asSet *parser.AsSet) error {
+	return s.insert("as_sets", &asSet.BaseObject,
+		[]interface{}{asSet.Name},
+		children{"descr": asSet.Description, "members": asSet.Members, "mbrs_by_ref": asSet.MbrsByRef})
+}
+
+func (s *Storage) SaveRouteSet(routeSet *parser.RouteSet) error {
+	return s.insert("route_sets", &routeSet.BaseObject,
+		[]interface{}{routeSet.Name},
+		children{"descr": routeSet.Description, "members": routeSet.Members, "mp_members": routeSet.MpMembers, "mbrs_by_ref": routeSet.MbrsByRef})
+}
+
+func (s *Storage) SaveFilterSet(filterSet *parser.FilterSet) error {
+	return s.insert("filter_sets", &filterSet.BaseObject,
+		[]interface{}{filterSet.Name, filterSet.Filter, filterSet.MpFilter},
+		children{"descr": filterSet.Description})
+}
+
+func (s *Storage) SavePeeringSet(peeringSet *parser.PeeringSet) error {
+	return s.insert("peering_sets", &peeringSet.BaseObject,
+		[]interface{}{peeringSet.Name},
+		children{"descr": peeringSet.Description, "peering": peeringSet.Peering, "mp_peering": peeringSet.MpPeering})
+}
+
+func (s *Storage) SaveRtrSet(rtrSet *parser.RtrSet) error {
+	return s.insert("rtr_sets", &rtrSet.BaseObject,
+		[]interface{}{rtrSet.Name},
+		children{"descr": rtrSet.Description, "members": rtrSet.Members, "mp_members": rtrSet.MpMembers, "mbrs_by_ref": rtrSet.MbrsByRef})
+}
+
+// Close replaces the rows stored earlier for the source with the objects written
+// by s in one transaction. The DB stays open for other sources.
+func (s *Storage) Close() error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.transaction(func(tx *sql.Tx) error {
+		if err := deleteSource(tx, s.source); err != nil {
+			return err
+		}
+		return renameSource(tx, s.staging, s.source)
+	})
+}
+
+// Abort drops the objects written by s and keeps the rows stored earlier for the source
+func (s *Storage) Abort() error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.transaction(func(tx *sql.Tx) error {
+		return deleteSource(tx, s.staging)
+	})
+}
+
+func (s *Storage) insert(tableName string, base *parser.BaseObject, values []interface{}, multi children) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stmt, err := s.db.stmt(tableName, insertStatement(tableByName[tableName]))
+	if err != nil {
+		return err
+	}
+
+	args := append([]interface{}{s.staging, base.Key, timeValue(base.Created), timeValue(base.LastModified)}, values...)
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert into %s: %w", tableName, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get id of %s row: %w", tableName, err)
+	}
+
+	multi["mnt_by"] = base.MntBy
+	multi["admin_c"] = base.AdminC
+	multi["tech_c"] = base.TechC
+	for name, list := range multi {
+		if len(list) == 0 {
+			continue
+		}
+		childStmt, err := s.db.stmt(name, childInsertStatement(name))
+		if err != nil {
+			return err
+		}
+		for position, value := range list {
+			if _, err := childStmt.Exec(s.staging, tableName, id, position, value); err != nil {
+				return fmt.Errorf("failed to insert into %s: %w", name, err)
+			}
+		}
+	}
+
+	s.db.pending++
+	if s.db.pending >= batchSize {
+		return s.db.commit()
+	}
+	return nil
+}
@@ -2,13 +2,18 @@ package rirs
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
 
 	"github.com/aredoff/rirs/fs"
 
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/aredoff/rirs/parser"
 )
@@ -17,10 +22,29 @@ const (
 	bufferSize = 5 * 1024 * 1024 // 5MB
 )
 
+// storage writes one JSON object per type, mapping object keys to objects.
+//
+// Objects are keyed by their RPSL primary key (see parser.Object.PrimaryKey), so a route
+// registered with several origins gets one entry per origin. When a key still repeats
+// within a type, for example when a source publishes overlapping dumps, later
+// objects get "#2", "#3", ... appended to the key in order of appearance.
+//
+// It is safe for concurrent use. Files parsed in parallel should each be written
+// through their own part, so the suffixes do not depend on which file is parsed first.
 type storage struct {
+	mu      sync.Mutex
 	folder  *fs.Folder
 	writers map[string]*bufio.Writer
 	files   map[string]*os.File
+	entries map[string]int
+	// keys counts every key written per type. Keys are stored as hashes to keep
+	// memory flat on large dumps; a collision only adds a needless suffix.
+	keys map[string]map[uint64]int
+	// parts are written into the files by Close, in order of their index
+	parts map[int]*storage
+	// spool is set on a part, which writes "type\tkey\tobject" lines to it instead
+	spool     *bufio.Writer
+	spoolFile *os.File
 }
 
 func NewStorage(folder *fs.Folder) (*storage, error) {
@@ -28,13 +52,15 @@ func NewStorage(folder *fs.Folder) (*storage, error) {
 		folder:  folder,
 		writers: make(map[string]*bufio.Writer),
 		files:   make(map[string]*os.File),
+		entries: make(map[string]int),
+		keys:    make(map[string]map[uint64]int),
+		parts:   make(map[int]*storage),
 	}
 
 	// Initialize writers for each type
-	types := []string{"asns", "inetnums", "routes", "routes6", "persons", "organizations", "domains"}
-	for _, t := range types {
-		if err := storage.initWriter(t); err != nil {
-			return nil, fmt.Errorf("failed to initialize writer for %s: %w", t, err)
+	for _, t := range objectTypes {
+		if err := storage.initWriter(t.file); err != nil {
+			return nil, fmt.Errorf("failed to initialize writer for %s: %w", t.file, err)
 		}
 	}
 
@@ -57,6 +83,7 @@ func (s *storage) initWriter(objType string) error {
 
 	s.files[objType] = file
 	s.writers[objType] = writer
+	s.keys[objType] = make(map[uint64]int)
 	return nil
 }
 
@@ -68,18 +95,34 @@ func (s *storage) SaveInetNum(inetnum *parser.InetNum) error {
 	return s.saveObject("inetnums", inetnum.IPRange, inetnum)
 }
 
+func (s *storage) SaveInet6Num(inet6num *parser.Inet6Num) error {
+	return s.saveObject("inet6nums", inet6num.Prefix, inet6num)
+}
+
 func (s *storage) SaveRoute(route *parser.Route) error {
-	return s.saveObject("routes", route.Prefix, route)
+	return s.saveObject("routes", route.Key, route)
 }
 
 func (s *storage) SaveRoute6(route6 *parser.Route6) error {
-	return s.saveObject("routes6", route6.Prefix, route6)
+	return s.saveObject("routes6", route6.Key, route6)
 }
 
 func (s *storage) SavePerson(person *parser.Person) error {
 	return s.saveObject("persons", person.NicHdl, person)
 }
 
+func (s *storage) SaveRole(role *parser.Role) error {
+	return s.saveObject("roles", role.NicHdl, role)
+}
+
+func (s *storage) SaveMntner(mntner *parser.Mntner) error {
+	return s.saveObject("mntners", mntner.Mntner, mntner)
+}
+
+func (s *storage) SaveIrt(irt *parser.Irt) error {
+	return s.saveObject("irts", irt.Irt, irt)
+}
+
 func (s *storage) SaveOrganization(org *parser.Organization) error {
 	return s.saveObject("organizations", org.OrgID, org)
 }
@@ -88,18 +131,62 @@ func (s *storage) SaveDomain(domain *parser.Domain) error {
 	return s.saveObject("domains", domain.Domain, domain)
 }
 
+func (s *storage) SaveAsSet(asSet *parser.AsSet) error {
+	return s.saveObject("as-sets", asSet.Name, asSet)
+}
+
+func (s *storage) SaveRouteSet(routeSet *parser.RouteSet) error {
+	return s.saveObject("route-sets", routeSet.Name, routeSet)
+}
+
+func (s *storage) SaveFilterSet(filterSet *parser.FilterSet) error {
+	return s.saveObject("filter-sets", filterSet.Name, filterSet)
+}
+
+func (s *storage) SavePeeringSet(peeringSet *parser.PeeringSet) error {
+	return s.saveObject("peering-sets", peeringSet.Name, peeringSet)
+}
+
+func (s *storage) SaveRtrSet(rtrSet *parser.RtrSet) error {
+	return s.saveObject("rtr-sets", rtrSet.Name, rtrSet)
+}
+
 func (s *storage) saveObject(objType, key string, obj interface{}) error {
+	// Marshal before taking the lock, it is the expensive part
+	jsonData, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal object: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.spool != nil {
+		return s.spoolObject(objType, key, jsonData)
+	}
+	return s.writeEntry(objType, key, jsonData)
+}
+
+// writeEntry writes one object under a unique key, the caller holds s.mu
+func (s *storage) writeEntry(objType, key string, jsonData []byte) error {
 	writer, ok := s.writers[objType]
 	if !ok {
 		return fmt.Errorf("writer for %s not initialized", objType)
 	}
 
-	jsonData, err := json.Marshal(obj)
+	jsonKey, err := json.Marshal(s.uniqueKey(objType, key))
 	if err != nil {
-		return fmt.Errorf("failed to marshal object: %w", err)
+		return fmt.Errorf("failed to marshal key: %w", err)
 	}
 
-	if _, err := writer.WriteString(fmt.Sprintf("  \"%s\": %s", key, string(jsonData))); err != nil {
+	if s.entries[objType] > 0 {
+		if _, err := writer.WriteString(",\n"); err != nil {
+			return fmt.Errorf("failed to write separator: %w", err)
+		}
+	}
+	s.entries[objType]++
+
+	if _, err := fmt.Fprintf(writer, "  %s: %s", jsonKey, jsonData); err != nil {
 		return fmt.Errorf("failed to write object: %w", err)
 	}
 
@@ -112,8 +199,123 @@ func (s *storage) saveObject(objType, key string, obj interface{}) error {
 	return nil
 }
 
-func (s *storage) Close() error {
+func (s *storage) uniqueKey(objType, key string) string {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+
+	keys := s.keys[objType]
+	keys[sum]++
+	if n := keys[sum]; n > 1 {
+		return fmt.Sprintf("%s#%d", key, n)
+	}
+	return key
+}
+
+// part returns a storage for the objects of input file i of the source. Parts spool
+// their objects into a temporary file of the folder, and Close writes them in order
+// of i, numbering repeated keys as if the files had been parsed one after another.
+func (s *storage) part(i int) (parser.Storage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.parts[i]; ok {
+		return nil, fmt.Errorf("part %d already exists", i)
+	}
+	file, err := os.CreateTemp(s.folder.Path(), fmt.Sprintf(".part-%d-*", i))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create part %d: %w", i, err)
+	}
+	part := &storage{
+		folder:    s.folder,
+		spool:     bufio.NewWriterSize(file, bufferSize),
+		spoolFile: file,
+	}
+	s.parts[i] = part
+	return part, nil
+}
+
+// spoolObject writes one object of a part. Neither the marshalled key nor the
+// object hold raw tabs or newlines, so a line splits back into its fields.
+func (s *storage) spoolObject(objType, key string, jsonData []byte) error {
+	jsonKey, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key: %w", err)
+	}
+	if _, err := fmt.Fprintf(s.spool, "%s\t%s\t%s\n", objType, jsonKey, jsonData); err != nil {
+		return fmt.Errorf("failed to spool object: %w", err)
+	}
+	return nil
+}
+
+// mergeParts writes the spooled objects of all parts in order and removes their
+// files, the caller holds s.mu
+func (s *storage) mergeParts() error {
+	indexes := make([]int, 0, len(s.parts))
+	for i := range s.parts {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
 	var lastErr error
+	for _, i := range indexes {
+		if err := s.mergePart(s.parts[i]); err != nil {
+			lastErr = fmt.Errorf("failed to merge part %d: %w", i, err)
+		}
+	}
+	s.parts = make(map[int]*storage)
+	return lastErr
+}
+
+func (s *storage) mergePart(part *storage) error {
+	part.mu.Lock()
+	defer part.mu.Unlock()
+
+	file := part.spoolFile
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := part.spool.Flush(); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(file, bufferSize)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if err := s.writeSpoolLine(line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *storage) writeSpoolLine(line []byte) error {
+	fields := bytes.SplitN(bytes.TrimSuffix(line, []byte("\n")), []byte("\t"), 3)
+	if len(fields) != 3 {
+		return fmt.Errorf("malformed spool line %q", line)
+	}
+	var key string
+	if err := json.Unmarshal(fields[1], &key); err != nil {
+		return fmt.Errorf("malformed spool key %s: %w", fields[1], err)
+	}
+	return s.writeEntry(string(fields[0]), key, fields[2])
+}
+
+func (s *storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastErr := s.mergeParts()
 
 	// Close all writers and files
 	for objType, writer := range s.writers {
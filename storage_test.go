@@ -0,0 +1,156 @@
+package rirs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aredoff/rirs/fs"
+	"github.com/aredoff/rirs/parser"
+)
+
+func route(prefix, origin string) *parser.Route {
+	return &parser.Route{
+		BaseObject: parser.BaseObject{Key: prefix + origin},
+		Prefix:     prefix,
+		Origin:     origin,
+	}
+}
+
+func TestStorageKeys(t *testing.T) {
+	folder, err := fs.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := NewStorage(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saves := []func() error{
+		// The same route with two origins, and the exact same route twice
+		func() error { return storage.SaveRoute(route("193.0.0.0/21", "AS3333")) },
+		func() error { return storage.SaveRoute(route("193.0.0.0/21", "AS2121")) },
+		func() error { return storage.SaveRoute(route("193.0.0.0/21", "AS3333")) },
+		func() error { return storage.SaveRoute(route("193.0.0.0/21", "AS3333")) },
+		// Keys that need escaping in JSON
+		func() error { return storage.SaveMntner(&parser.Mntner{Mntner: `QUOTE"MNT`}) },
+		func() error { return storage.SaveMntner(&parser.Mntner{Mntner: `BACK\SLASH-MNT`}) },
+		func() error { return storage.SaveMntner(&parser.Mntner{Mntner: "TAB\tMNT"}) },
+		func() error { return storage.SaveMntner(&parser.Mntner{Mntner: "TAB\tMNT"}) },
+		func() error { return storage.SaveMntner(&parser.Mntner{Mntner: "ÜNICODE-MNT"}) },
+		// Persons without a nic-hdl have an empty key
+		func() error { return storage.SavePerson(&parser.Person{Name: "No Handle"}) },
+		func() error { return storage.SavePerson(&parser.Person{Name: "No Handle Either"}) },
+	}
+	for _, save := range saves {
+		if err := save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every file must be valid JSON, including the types nothing was written to
+	files, err := filepath.Glob(filepath.Join(folder.Path(), "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(objectTypes) {
+		t.Fatalf("got %d files, want %d", len(files), len(objectTypes))
+	}
+	decoded := make(map[string]map[string]json.RawMessage)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(content, &entries); err != nil {
+			t.Fatalf("%s: %v", filepath.Base(file), err)
+		}
+		decoded[filepath.Base(file)] = entries
+	}
+
+	if n := len(decoded["asns.json"]); n != 0 {
+		t.Errorf("asns.json has %d entries, want 0", n)
+	}
+
+	tests := []struct {
+		file string
+		keys []string
+	}{
+		{"routes.json", []string{"193.0.0.0/21AS3333", "193.0.0.0/21AS2121", "193.0.0.0/21AS3333#2", "193.0.0.0/21AS3333#3"}},
+		{"mntners.json", []string{`QUOTE"MNT`, `BACK\SLASH-MNT`, "TAB\tMNT", "TAB\tMNT#2", "ÜNICODE-MNT"}},
+		{"persons.json", []string{"", "#2"}},
+	}
+	for _, tt := range tests {
+		entries := decoded[tt.file]
+		if len(entries) != len(tt.keys) {
+			t.Errorf("%s has %d entries, want %d", tt.file, len(entries), len(tt.keys))
+		}
+		for _, key := range tt.keys {
+			if _, ok := entries[key]; !ok {
+				t.Errorf("%s: missing key %q", tt.file, key)
+			}
+		}
+	}
+}
+
+func TestStorageParts(t *testing.T) {
+	folder, err := fs.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := NewStorage(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := storage.part(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := storage.part(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second file finishes first, its objects still come after those of the first
+	late := route("193.0.0.0/21", "AS3333")
+	late.Org = "ORG-SECOND"
+	second.SaveRoute(late)
+	second.SaveMntner(&parser.Mntner{Mntner: "TAB\tMNT"})
+	first.SaveRoute(route("193.0.0.0/21", "AS3333"))
+	first.SaveRoute(route("193.0.0.0/21", "AS3333"))
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(folder.GetPath("routes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var routes map[string]json.RawMessage
+	if err := json.Unmarshal(content, &routes); err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 3 || !strings.Contains(string(routes["193.0.0.0/21AS3333#3"]), "ORG-SECOND") {
+		t.Errorf("routes.json = %s, want the route of the second file under #3", content)
+	}
+	content, err = os.ReadFile(folder.GetPath("mntners.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mntners map[string]json.RawMessage
+	if err := json.Unmarshal(content, &mntners); err != nil || mntners["TAB\tMNT"] == nil {
+		t.Errorf("mntners.json = %s, %v", content, err)
+	}
+
+	leftovers, err := filepath.Glob(filepath.Join(folder.Path(), ".part-*"))
+	if err != nil || len(leftovers) > 0 {
+		t.Errorf("part files left behind: %v %v", leftovers, err)
+	}
+}
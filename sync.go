@@ -1,40 +1,344 @@
 package rirs
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aredoff/rirs/fs"
 	"github.com/aredoff/rirs/parser"
 )
 
 func (r *rir) Sync() error {
+	return r.SyncContext(context.Background())
+}
+
+// SyncContext is Sync that stops downloading and parsing once ctx is done and
+// returns ctx.Err(). With the built-in storages a source only replaces its
+// database folder once it is complete, so a cancelled sync keeps the previous one.
+// Storages from WithStorageFactory keep whatever was saved before the cancellation,
+// unless they implement Aborter. The set index behind Expand and PrefixList keeps
+// the previous objects of sources that fail, read back from their database folder
+// when they did not sync yet in this process. Sources written through a
+// StorageFactory have no folder and are left out until they sync.
+//
+// Sources and the files within them are downloaded and parsed in parallel, at most
+// WithConcurrency files at a time. The first error stops the other sources.
+func (r *rir) SyncContext(ctx context.Context) error {
+	group := newTaskGroup(ctx)
+	slots := make(chan struct{}, r.concurrency)
 	for _, source := range sources {
-		downloadDir, err := r.downloadFolder.SubFolder(source.Name)
-		if err != nil {
-			return err
+		group.run(func(ctx context.Context) error {
+			return r.syncSource(ctx, source, slots)
+		})
+	}
+	err := group.wait()
+	// Sources that synced before an error are updated all the same
+	if mergeErr := r.mergeSets(); mergeErr != nil && err == nil {
+		err = mergeErr
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
 		}
-		databaseDir, err := r.databaseFolder.SubFolder(source.Name)
+		return err
+	}
+	return nil
+}
+
+// syncSource downloads the files of source and parses them into a new database.
+// When no file changed since the database was written, the database is kept and
+// only the set index is rebuilt, from the folder of the built-in storages or from
+// the files when a StorageFactory is used. The set index of source is only
+// replaced when it succeeds.
+func (r *rir) syncSource(ctx context.Context, source source, slots chan struct{}) error {
+	downloadDir, err := r.downloadFolder.SubFolder(source.Name)
+	if err != nil {
+		return err
+	}
+	downloads, err := downloadSource(ctx, source, downloadDir, slots)
+	if err != nil {
+		return err
+	}
+
+	if !changed(downloads) && r.storageFactory == nil && r.databaseFolder.Exist(source.Name) {
+		folder, err := r.databaseFolder.SubFolder(source.Name)
 		if err != nil {
 			return err
 		}
-		storage, err := NewStorage(databaseDir)
-		if err != nil {
+		sets := NewSetIndex()
+		if err := sets.addFolder(folder); err != nil {
 			return err
 		}
-		defer storage.Close()
+		r.setSourceSets(source.Name, sets)
+		// The files may have been verified for the first time
+		return saveDownloadMetas(downloadDir, downloads)
+	}
+	if !changed(downloads) && r.storageFactory != nil {
+		// Metadata is only saved once a sync succeeded, so the storage already
+		// holds these files. Only the set index is built again when it is missing.
+		if !r.hasSourceSets(source.Name) {
+			sets := NewSetIndex()
+			if err := r.parseSource(ctx, downloads, discardStorage{}, sets, slots); err != nil {
+				return err
+			}
+			r.setSourceSets(source.Name, sets)
+		}
+		return saveDownloadMetas(downloadDir, downloads)
+	}
 
-		parser := parser.NewParser(storage)
-		for _, url := range source.httpDatabases {
-			filePath, err := downloadFile(downloadDir.Path(), url)
+	storage, err := r.newStorage(source.Name)
+	if err != nil {
+		return err
+	}
+
+	sets := NewSetIndex()
+	err = r.parseSource(ctx, downloads, storage, sets, slots)
+	if aborter, ok := storage.(Aborter); ok && err != nil {
+		if abortErr := aborter.Abort(); abortErr != nil {
+			return fmt.Errorf("%w (and failed to abort storage: %v)", err, abortErr)
+		}
+		return err
+	}
+	if closer, ok := storage.(io.Closer); ok {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close storage for %s: %w", source.Name, closeErr)
+		}
+	}
+	if err != nil {
+		if discardErr := r.discard(source.Name); discardErr != nil {
+			return fmt.Errorf("%w (and failed to remove staging folder: %v)", err, discardErr)
+		}
+		return err
+	}
+	if err := r.publish(source.Name); err != nil {
+		return err
+	}
+	r.setSourceSets(source.Name, sets)
+
+	// Only now the files are in the database, remember them for the next sync
+	if err := saveDownloadMetas(downloadDir, downloads); err != nil {
+		return err
+	}
+	return pruneDownloads(downloadDir, source)
+}
+
+func saveDownloadMetas(downloadDir *fs.Folder, downloads []*download) error {
+	for _, download := range downloads {
+		if err := saveDownloadMeta(downloadDir, download.meta); err != nil {
+			return fmt.Errorf("failed to save download metadata of %s: %w", download.meta.URL, err)
+		}
+	}
+	return nil
+}
+
+// downloadSource downloads the files of source in parallel, each holding one of slots
+func downloadSource(ctx context.Context, source source, downloadDir *fs.Folder, slots chan struct{}) ([]*download, error) {
+	downloads := make([]*download, len(source.httpDatabases))
+
+	group := newTaskGroup(ctx)
+	for i, url := range source.httpDatabases {
+		group.run(func(ctx context.Context) error {
+			if err := acquire(ctx, slots); err != nil {
+				return err
+			}
+			defer release(slots)
+
+			var download *download
+			var err error
+			if checksumURL, ok := source.checksums[url]; ok {
+				download, err = downloadVerified(ctx, downloadDir, url, checksumURL)
+			} else {
+				download, err = downloadFile(ctx, downloadDir, url)
+			}
 			if err != nil {
 				return err
 			}
-			err = parser.ParseGZFile(filePath)
+			downloads[i] = download
+			return nil
+		})
+	}
+	if err := group.wait(); err != nil {
+		return nil, err
+	}
+	return downloads, nil
+}
+
+// partitioned is implemented by storages whose output depends on the order objects
+// are saved in. Files parsed in parallel each get their own part, see storage.part.
+type partitioned interface {
+	part(i int) (parser.Storage, error)
+}
+
+// parseSource parses downloaded files into storage and records the objects the
+// set index needs in sets. Files are parsed in parallel, each holding one of slots,
+// and a partitioned storage gets one part per file, so repeated keys are numbered
+// the same whichever file finishes first. With a concurrency of 1 the files are
+// parsed one after another in the order of the source URLs.
+func (r *rir) parseSource(ctx context.Context, downloads []*download, storage parser.Storage, sets *SetIndex, slots chan struct{}) error {
+	if r.concurrency == 1 || len(downloads) == 1 {
+		indexed := &indexStorage{Storage: storage, sets: sets}
+		for _, download := range downloads {
+			if err := acquire(ctx, slots); err != nil {
+				return err
+			}
+			err := parser.NewParser(indexed).ParseGZFileContext(ctx, download.path)
+			release(slots)
 			if err != nil {
 				return err
 			}
 		}
-		err = downloadDir.Clear()
-		if err != nil {
+		return nil
+	}
+
+	targets := make([]parser.Storage, len(downloads))
+	for i := range downloads {
+		targets[i] = storage
+		if partitioned, ok := storage.(partitioned); ok {
+			part, err := partitioned.part(i)
+			if err != nil {
+				return err
+			}
+			targets[i] = part
+		}
+	}
+
+	group := newTaskGroup(ctx)
+	for i, download := range downloads {
+		group.run(func(ctx context.Context) error {
+			if err := acquire(ctx, slots); err != nil {
+				return err
+			}
+			defer release(slots)
+
+			indexed := &indexStorage{Storage: targets[i], sets: sets}
+			return parser.NewParser(indexed).ParseGZFileContext(ctx, download.path)
+		})
+	}
+	return group.wait()
+}
+
+func (r *rir) setSourceSets(source string, sets *SetIndex) {
+	r.setsMu.Lock()
+	defer r.setsMu.Unlock()
+
+	r.sourceSets[source] = sets
+}
+
+func (r *rir) hasSourceSets(source string) bool {
+	r.setsMu.Lock()
+	defer r.setsMu.Unlock()
+
+	_, ok := r.sourceSets[source]
+	return ok
+}
+
+// mergeSets rebuilds the set index from the indexes of the synced sources. A source
+// without one failed before it synced in this process, its published folder is used.
+func (r *rir) mergeSets() error {
+	r.setsMu.Lock()
+	defer r.setsMu.Unlock()
+
+	indexes := make([]*SetIndex, 0, len(sources))
+	for _, source := range sources {
+		sets, ok := r.sourceSets[source.Name]
+		if !ok && r.storageFactory == nil && r.databaseFolder.Exist(source.Name) {
+			folder, err := r.databaseFolder.SubFolder(source.Name)
+			if err != nil {
+				return err
+			}
+			sets = NewSetIndex()
+			if err := sets.addFolder(folder); err != nil {
+				return fmt.Errorf("failed to index sets of %s: %w", source.Name, err)
+			}
+			r.sourceSets[source.Name] = sets
+			ok = true
+		}
+		if ok {
+			indexes = append(indexes, sets)
+		}
+	}
+	r.sets.replace(indexes)
+	return nil
+}
+
+func changed(downloads []*download) bool {
+	for _, download := range downloads {
+		if download.changed {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneDownloads removes the files of downloadDir that belong to no URL of source
+func pruneDownloads(downloadDir *fs.Folder, source source) error {
+	keep := make(map[string]bool, 2*len(source.httpDatabases))
+	for _, url := range source.httpDatabases {
+		keep[downloadName(url)] = true
+		keep[metaName(url)] = true
+	}
+
+	entries, err := os.ReadDir(downloadDir.Path())
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if keep[entry.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(downloadDir.GetPath(entry.Name())); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+func acquire(ctx context.Context, slots chan struct{}) error {
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func release(slots chan struct{}) {
+	<-slots
+}
+
+// taskGroup runs tasks in goroutines and cancels the context they share once one fails
+type taskGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+func newTaskGroup(ctx context.Context) *taskGroup {
+	ctx, cancel := context.WithCancel(ctx)
+	return &taskGroup{ctx: ctx, cancel: cancel}
+}
+
+func (g *taskGroup) run(task func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := task(g.ctx); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// wait waits for all tasks and returns the first error
+func (g *taskGroup) wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
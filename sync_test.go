@@ -0,0 +1,163 @@
+package rirs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aredoff/rirs/fs"
+	"github.com/aredoff/rirs/parser"
+)
+
+func gzipDump(t *testing.T, dump string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(dump)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withSources replaces the synced sources for the duration of the test
+func withSources(t *testing.T, replacement []source) {
+	previous := sources
+	sources = replacement
+	t.Cleanup(func() { sources = previous })
+}
+
+func TestSyncKeepsSetsOfFailedSourceAfterRestart(t *testing.T) {
+	dump := gzipDump(t, "route:          192.0.2.0/24\norigin:         AS64500\nsource:         TEST\n")
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(dump)
+	}))
+	defer server.Close()
+	withSources(t, []source{{Name: "test", httpDatabases: []string{server.URL + "/test.db.gz"}}})
+
+	folder, err := fs.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := New(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.SyncContext(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process whose first sync fails still knows the published routes
+	failing = true
+	second, err := New(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := second.SyncContext(t.Context()); err == nil {
+		t.Fatal("sync succeeded against a failing server")
+	}
+	prefixes, err := second.PrefixList(t.Context(), "AS64500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+	if !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("PrefixList() = %v, want %v", prefixes, want)
+	}
+}
+
+func TestSyncParallelFilesNumberKeysInURLOrder(t *testing.T) {
+	dumps := map[string][]byte{
+		"/first.db.gz":  gzipDump(t, "route:          192.0.2.0/24\norigin:         AS64500\norg:            ORG-FIRST\nsource:         TEST\n"),
+		"/second.db.gz": gzipDump(t, "route:          192.0.2.0/24\norigin:         AS64500\norg:            ORG-SECOND\nsource:         TEST\n"),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(dumps[r.URL.Path])
+	}))
+	defer server.Close()
+	withSources(t, []source{{Name: "test", httpDatabases: []string{server.URL + "/first.db.gz", server.URL + "/second.db.gz"}}})
+
+	for range 5 {
+		folder, err := fs.New(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := New(folder, WithConcurrency(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.SyncContext(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(folder.Path(), "database", "test", "routes.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var routes map[string]json.RawMessage
+		if err := json.Unmarshal(content, &routes); err != nil {
+			t.Fatal(err)
+		}
+		first, second := string(routes["192.0.2.0/24AS64500"]), string(routes["192.0.2.0/24AS64500#2"])
+		if !strings.Contains(first, "ORG-FIRST") || !strings.Contains(second, "ORG-SECOND") {
+			t.Fatalf("routes.json = %s, want the route of the first URL without a suffix", content)
+		}
+	}
+}
+
+func TestSyncSkipsUnchangedFactorySource(t *testing.T) {
+	dump := gzipDump(t, "route:          192.0.2.0/24\norigin:         AS64500\nsource:         TEST\n")
+	modified := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "test.db.gz", modified, bytes.NewReader(dump))
+	}))
+	defer server.Close()
+	withSources(t, []source{{Name: "test", httpDatabases: []string{server.URL + "/test.db.gz"}}})
+
+	folder, err := fs.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := 0
+	factory := func(string) (parser.Storage, error) {
+		created++
+		return parser.NewRipeDatabase(), nil
+	}
+	for range 2 {
+		// Every sync runs in a new process, the set index is not kept between them
+		r, err := New(folder, WithStorageFactory(factory))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.SyncContext(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+		prefixes, err := r.PrefixList(t.Context(), "AS64500")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+		if !reflect.DeepEqual(prefixes, want) {
+			t.Errorf("PrefixList() = %v, want %v", prefixes, want)
+		}
+	}
+	if created != 1 {
+		t.Errorf("storage created %d times, want once", created)
+	}
+}
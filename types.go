@@ -0,0 +1,122 @@
+package rirs
+
+import "github.com/aredoff/rirs/parser"
+
+// objectType ties an object class to the names it is written under and lets
+// stored objects be decoded and replayed into any parser.Storage
+type objectType struct {
+	// class is the RPSL class, used as the type of JSON Lines records
+	class string
+	// file is the name of the JSON file without extension
+	file string
+	new  func() interface{}
+	save func(storage parser.Storage, obj interface{}) error
+}
+
+var objectTypes = []objectType{
+	{
+		class: "aut-num",
+		file:  "asns",
+		new:   func() interface{} { return &parser.ASN{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveASN(obj.(*parser.ASN)) },
+	},
+	{
+		class: "inetnum",
+		file:  "inetnums",
+		new:   func() interface{} { return &parser.InetNum{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveInetNum(obj.(*parser.InetNum)) },
+	},
+	{
+		class: "inet6num",
+		file:  "inet6nums",
+		new:   func() interface{} { return &parser.Inet6Num{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveInet6Num(obj.(*parser.Inet6Num)) },
+	},
+	{
+		class: "route",
+		file:  "routes",
+		new:   func() interface{} { return &parser.Route{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveRoute(obj.(*parser.Route)) },
+	},
+	{
+		class: "route6",
+		file:  "routes6",
+		new:   func() interface{} { return &parser.Route6{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveRoute6(obj.(*parser.Route6)) },
+	},
+	{
+		class: "person",
+		file:  "persons",
+		new:   func() interface{} { return &parser.Person{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SavePerson(obj.(*parser.Person)) },
+	},
+	{
+		class: "role",
+		file:  "roles",
+		new:   func() interface{} { return &parser.Role{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveRole(obj.(*parser.Role)) },
+	},
+	{
+		class: "mntner",
+		file:  "mntners",
+		new:   func() interface{} { return &parser.Mntner{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveMntner(obj.(*parser.Mntner)) },
+	},
+	{
+		class: "irt",
+		file:  "irts",
+		new:   func() interface{} { return &parser.Irt{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveIrt(obj.(*parser.Irt)) },
+	},
+	{
+		class: "organisation",
+		file:  "organizations",
+		new:   func() interface{} { return &parser.Organization{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveOrganization(obj.(*parser.Organization)) },
+	},
+	{
+		class: "domain",
+		file:  "domains",
+		new:   func() interface{} { return &parser.Domain{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveDomain(obj.(*parser.Domain)) },
+	},
+	{
+		class: "as-set",
+		file:  "as-sets",
+		new:   func() interface{} { return &parser.AsSet{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveAsSet(obj.(*parser.AsSet)) },
+	},
+	{
+		class: "route-set",
+		file:  "route-sets",
+		new:   func() interface{} { return &parser.RouteSet{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveRouteSet(obj.(*parser.RouteSet)) },
+	},
+	{
+		class: "filter-set",
+		file:  "filter-sets",
+		new:   func() interface{} { return &parser.FilterSet{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveFilterSet(obj.(*parser.FilterSet)) },
+	},
+	{
+		class: "peering-set",
+		file:  "peering-sets",
+		new:   func() interface{} { return &parser.PeeringSet{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SavePeeringSet(obj.(*parser.PeeringSet)) },
+	},
+	{
+		class: "rtr-set",
+		file:  "rtr-sets",
+		new:   func() interface{} { return &parser.RtrSet{} },
+		save:  func(s parser.Storage, obj interface{}) error { return s.SaveRtrSet(obj.(*parser.RtrSet)) },
+	},
+}
+
+func objectTypeByClass(class string) (objectType, bool) {
+	for _, t := range objectTypes {
+		if t.class == class {
+			return t, true
+		}
+	}
+	return objectType{}, false
+}
@@ -1,47 +1,252 @@
 package rirs
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/aredoff/rirs/fs"
 )
 
-func downloadFile(dirPath string, url string) (string, error) {
-	resp, err := http.Get(url)
+// downloadMeta records the version of a URL a file holds: for a download that made
+// it into a database, so the next sync can ask the server whether it changed, and
+// for a .part file, so it is only resumed against the same version
+type downloadMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Verified is set once the file matched its published checksum
+	Verified bool `json:"verified,omitempty"`
+}
+
+// download is a file fetched by downloadFile
+type download struct {
+	path string
+	// changed is false when the server answered 304 Not Modified and the file
+	// from the previous download was kept
+	changed bool
+	meta    downloadMeta
+}
+
+const (
+	// downloadAttempts is how many times an interrupted download is resumed
+	downloadAttempts = 5
+	retryDelay       = 2 * time.Second
+)
+
+// downloadFile downloads url into dir. When a previous download of url is still
+// in dir the request is conditional on its ETag and Last-Modified.
+//
+// The file is written to a .part file first and only renamed into place once
+// complete. Interrupted transfers are resumed with Range requests, both on the
+// next attempt and on the next sync when ctx was cancelled.
+func downloadFile(ctx context.Context, dir *fs.Folder, url string) (*download, error) {
+	var err error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		var result *download
+		var retry bool
+		result, retry, err = fetch(ctx, dir, url)
+		if err == nil {
+			return result, nil
+		}
+		if !retry || ctx.Err() != nil {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * retryDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, fmt.Errorf("failed to download %s after %d attempts: %w", url, downloadAttempts, err)
+}
+
+// fetch makes one attempt at downloading url. retry reports whether the error
+// is worth another attempt.
+func fetch(ctx context.Context, dir *fs.Folder, url string) (result *download, retry bool, err error) {
+	fileName := downloadName(url)
+	partName := fileName + ".part"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating request: %w", err)
+	}
+
+	// Resume a partial file when we know which version of the resource it holds
+	var offset int64
+	part, havePart := loadPartMeta(dir, url)
+	if validator := rangeValidator(part); havePart && validator != "" {
+		if info, err := os.Stat(dir.GetPath(partName)); err == nil && info.Size() > 0 {
+			offset = info.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", validator)
+		}
+	}
+
+	previous, havePrevious := loadDownloadMeta(dir, url)
+	if havePrevious && offset == 0 {
+		if previous.ETag != "" {
+			req.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			req.Header.Set("If-Modified-Since", previous.LastModified)
+		} else if modified, err := dir.LastModified(fileName); err == nil {
+			req.Header.Set("If-Modified-Since", modified.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error making request: %v", err)
+		return nil, true, fmt.Errorf("error making request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status: %s", resp.Status)
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusNotModified && havePrevious && offset == 0:
+		return &download{path: dir.GetPath(fileName), changed: false, meta: previous}, false, nil
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp) == offset:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		// A fresh download, or the server ignored the range because the resource changed
+		flags |= os.O_TRUNC
+		part = downloadMeta{
+			URL:          url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+		if err := savePartMeta(dir, part); err != nil {
+			return nil, false, fmt.Errorf("failed to save download metadata: %w", err)
+		}
+	case resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file cannot be continued, start over
+		removePart(dir, url)
+		return nil, true, fmt.Errorf("cannot resume download of %s: %s", url, resp.Status)
+	default:
+		return nil, resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	out, err := os.OpenFile(dir.GetPath(partName), flags, 0644)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create file: %v", err)
+	}
+	_, err = io.Copy(out, resp.Body)
+	closeErr := out.Close()
+	if err != nil {
+		// Keep what arrived, the next attempt resumes from there
+		return nil, true, fmt.Errorf("error writing file: %w", err)
+	}
+	if closeErr != nil {
+		return nil, false, fmt.Errorf("error writing file: %w", closeErr)
+	}
+
+	if err := os.Rename(dir.GetPath(partName), dir.GetPath(fileName)); err != nil {
+		return nil, false, fmt.Errorf("failed to move download into place: %w", err)
+	}
+	os.Remove(dir.GetPath(partMetaName(url)))
+
+	return &download{path: dir.GetPath(fileName), changed: true, meta: part}, false, nil
+}
+
+// rangeValidator returns the If-Range value for a partial download. Weak ETags
+// cannot be used there, so they fall back to Last-Modified.
+func rangeValidator(meta downloadMeta) string {
+	if meta.ETag != "" && !strings.HasPrefix(meta.ETag, "W/") {
+		return meta.ETag
+	}
+	return meta.LastModified
+}
+
+// contentRangeStart returns the first byte of a "bytes 100-199/200" Content-Range
+func contentRangeStart(resp *http.Response) int64 {
+	value, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, _ := strings.Cut(value, "-")
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
 	}
+	return n
+}
+
+// downloadName returns the file name url is downloaded to. The name is prefixed
+// with a hash of url, sources publish several dumps under the same name.
+func downloadName(url string) string {
+	h := fnv.New32a()
+	h.Write([]byte(url))
 
 	fileName := filepath.Base(url)
 	if fileName == "." || fileName == "/" {
-		fileName = fmt.Sprintf("index-%s", uuid.New().String())
+		fileName = "index"
+	}
+	return fmt.Sprintf("%08x-%s", h.Sum32(), fileName)
+}
+
+func metaName(url string) string {
+	return downloadName(url) + ".meta"
+}
+
+func partMetaName(url string) string {
+	return downloadName(url) + ".part.meta"
+}
+
+// loadDownloadMeta returns the metadata of the previous download of url. It is
+// only usable when the downloaded file is still there.
+func loadDownloadMeta(dir *fs.Folder, url string) (downloadMeta, bool) {
+	if !dir.Exist(downloadName(url)) {
+		return downloadMeta{}, false
 	}
+	return readMeta(dir, metaName(url), url)
+}
 
-	filePath := filepath.Join(dirPath, fileName)
+func saveDownloadMeta(dir *fs.Folder, meta downloadMeta) error {
+	return writeMeta(dir, metaName(meta.URL), meta)
+}
 
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %v", err)
+// loadPartMeta returns the metadata of the response a partial download of url started with
+func loadPartMeta(dir *fs.Folder, url string) (downloadMeta, bool) {
+	if !dir.Exist(downloadName(url) + ".part") {
+		return downloadMeta{}, false
 	}
+	return readMeta(dir, partMetaName(url), url)
+}
+
+func savePartMeta(dir *fs.Folder, meta downloadMeta) error {
+	return writeMeta(dir, partMetaName(meta.URL), meta)
+}
+
+func removePart(dir *fs.Folder, url string) {
+	os.Remove(dir.GetPath(downloadName(url) + ".part"))
+	os.Remove(dir.GetPath(partMetaName(url)))
+}
 
-	out, err := os.Create(filePath)
+func readMeta(dir *fs.Folder, name, url string) (downloadMeta, bool) {
+	content, err := dir.GetContent(name)
 	if err != nil {
-		return "", fmt.Errorf("failed to create file: %v", err)
+		return downloadMeta{}, false
+	}
+	var meta downloadMeta
+	if err := json.Unmarshal(content, &meta); err != nil || meta.URL != url {
+		return downloadMeta{}, false
 	}
-	defer out.Close()
+	return meta, true
+}
 
-	_, err = io.Copy(out, resp.Body)
+func writeMeta(dir *fs.Folder, name string, meta downloadMeta) error {
+	content, err := json.Marshal(meta)
 	if err != nil {
-		return "", fmt.Errorf("error writing file: %v", err)
+		return err
 	}
-
-	return filePath, nil
+	return dir.PutContent(name, content)
 }
@@ -0,0 +1,143 @@
+package rirs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aredoff/rirs/fs"
+)
+
+var dumpContent = []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+
+// serveDump serves content with etag and records the headers of every request
+func serveDump(t *testing.T, content []byte, etag string) (string, *[]http.Header) {
+	t.Helper()
+	var requests []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Clone())
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "test.db.gz", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/test.db.gz", &requests
+}
+
+func tempFolder(t *testing.T) *fs.Folder {
+	t.Helper()
+	dir, err := fs.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// writePart leaves a partial download of url in dir, started from a response with etag
+func writePart(t *testing.T, dir *fs.Folder, url string, content []byte, etag string) {
+	t.Helper()
+	if err := dir.PutContent(downloadName(url)+".part", content); err != nil {
+		t.Fatal(err)
+	}
+	if err := savePartMeta(dir, downloadMeta{URL: url, ETag: etag}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertDownloaded(t *testing.T, dir *fs.Folder, url string, result *download, want []byte) {
+	t.Helper()
+	content, err := os.ReadFile(result.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, want) {
+		t.Errorf("downloaded %q, want %q", content, want)
+	}
+	if dir.Exist(downloadName(url)+".part") || dir.Exist(partMetaName(url)) {
+		t.Error("partial download left behind")
+	}
+}
+
+func TestFetchResumesPart(t *testing.T) {
+	url, requests := serveDump(t, dumpContent, `"v1"`)
+	dir := tempFolder(t)
+	// Only the missing bytes are requested, the part is kept as it is
+	part := []byte("XXXXXXXXXX")
+	writePart(t, dir, url, part, `"v1"`)
+
+	result, _, err := fetch(t.Context(), dir, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.changed || result.meta.ETag != `"v1"` {
+		t.Errorf("fetch() = changed %v, meta %+v", result.changed, result.meta)
+	}
+	header := (*requests)[0]
+	if header.Get("Range") != "bytes=10-" || header.Get("If-Range") != `"v1"` {
+		t.Errorf("Range %q, If-Range %q, want bytes=10- and \"v1\"", header.Get("Range"), header.Get("If-Range"))
+	}
+	assertDownloaded(t, dir, url, result, append(part, dumpContent[10:]...))
+}
+
+func TestFetchRestartsChangedPart(t *testing.T) {
+	// The server sends the whole new version instead of 206 when If-Range does not match
+	url, _ := serveDump(t, dumpContent, `"v2"`)
+	dir := tempFolder(t)
+	writePart(t, dir, url, []byte("stale"), `"v1"`)
+
+	result, _, err := fetch(t.Context(), dir, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.meta.ETag != `"v2"` {
+		t.Errorf("meta.ETag = %q, want \"v2\"", result.meta.ETag)
+	}
+	assertDownloaded(t, dir, url, result, dumpContent)
+}
+
+func TestFetchRemovesUnsatisfiablePart(t *testing.T) {
+	url, _ := serveDump(t, dumpContent, `"v1"`)
+	dir := tempFolder(t)
+	writePart(t, dir, url, append(dumpContent, "overflow"...), `"v1"`)
+
+	_, retry, err := fetch(t.Context(), dir, url)
+	if err == nil || !retry {
+		t.Fatalf("fetch() = retry %v, err %v, want a retryable error", retry, err)
+	}
+	if dir.Exist(downloadName(url)+".part") || dir.Exist(partMetaName(url)) {
+		t.Error("unsatisfiable partial download kept")
+	}
+
+	result, _, err := fetch(t.Context(), dir, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDownloaded(t, dir, url, result, dumpContent)
+}
+
+func TestFetchNotModified(t *testing.T) {
+	url, requests := serveDump(t, dumpContent, `"v1"`)
+	dir := tempFolder(t)
+
+	first, _, err := fetch(t.Context(), dir, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveDownloadMeta(dir, first.meta); err != nil {
+		t.Fatal(err)
+	}
+
+	second, _, err := fetch(t.Context(), dir, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.changed || second.path != first.path || second.meta != first.meta {
+		t.Errorf("fetch() = %+v, want the unchanged download %+v", second, first)
+	}
+	if got := (*requests)[1].Get("If-None-Match"); got != `"v1"` {
+		t.Errorf("If-None-Match = %q, want \"v1\"", got)
+	}
+	assertDownloaded(t, dir, url, second, dumpContent)
+}